@@ -0,0 +1,246 @@
+package vapi
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/chriscow/minds"
+)
+
+// Vapi's default analysis prompts, used for plans that have no messages
+const (
+	defaultSummaryPrompt = "You are an expert note-taker. You will be given a transcript of a call. Summarize the call in 2-3 sentences, if applicable."
+
+	defaultStructuredDataPrompt = "You are an expert data extractor. You will be given a transcript of a call. Extract structured data per the JSON Schema. DO NOT return anything except the structured data.\n\nJson Schema:\n{{schema}}\n\nOnly respond with the JSON."
+
+	defaultSuccessEvaluationPrompt = "You are an expert call evaluator. You will be given a transcript of a call and the system prompt of the AI participant. Determine if the call was successful based on the objectives inferred from the system prompt. DO NOT return anything except the result.\n\nRubric:\n{{rubric}}\n\nOnly respond with the result."
+)
+
+// rubricDescriptions are what {{rubric}} expands to for each rubric
+var rubricDescriptions = map[string]string{
+	RubricNumericScale:     "NumericScale: A scale of 1 to 10.",
+	RubricDescriptiveScale: "DescriptiveScale: A scale of Excellent, Good, Fair, Poor.",
+	RubricChecklist:        "Checklist: A checklist of criteria and their status.",
+	RubricMatrix:           "Matrix: A grid that evaluates multiple criteria across different performance levels.",
+	RubricPercentageScale:  "PercentageScale: A scale of 0% to 100%.",
+	RubricLikertScale:      "LikertScale: A scale of Strongly Agree, Agree, Neutral, Disagree, Strongly Disagree.",
+	RubricAutomaticRubric:  "AutomaticRubric: Automatically break down evaluation into several criteria, each with its own score.",
+	RubricPassFail:         "PassFail: A simple 'true' if call passed, 'false' if not.",
+}
+
+var analysisVariablePattern = regexp.MustCompile(`\{\{\s*(transcript|systemPrompt|rubric|schema)\s*\}\}`)
+
+// AnalysisRunner re-runs post-call analysis locally, so old calls can be
+// scored again after a plan or rubric changes:
+//
+//	runner := vapi.NewAnalysisRunner(provider)
+//	analysis, err := runner.Run(ctx, call, &vapi.AnalysisPlan{
+//		SuccessEvaluationPlan: &vapi.SuccessEvaluationPlan{Rubric: &rubric},
+//	})
+//
+// Plan messages go through the provider with the same variables Vapi
+// substitutes: {{transcript}}, {{systemPrompt}}, {{rubric}} and {{schema}}.
+type AnalysisRunner struct {
+	Provider minds.ContentGenerator
+	// Options are added to every request, e.g. minds.WithModel
+	Options []minds.RequestOption
+}
+
+// NewAnalysisRunner returns a runner sending requests to provider
+func NewAnalysisRunner(provider minds.ContentGenerator, opts ...minds.RequestOption) *AnalysisRunner {
+	return &AnalysisRunner{Provider: provider, Options: opts}
+}
+
+// Run executes the plans in plan against the call's transcript and system
+// prompt. With a nil plan the analysis plan of the call's assistant is used.
+// Only the plans that are set run: the summary plan, the structured data
+// plan when enabled, each StructuredDataMulti plan, and the success
+// evaluation plan unless disabled. Plans without messages use Vapi's
+// default prompts.
+func (r *AnalysisRunner) Run(ctx context.Context, call *Call, plan *AnalysisPlan) (*Analysis, error) {
+	if plan == nil {
+		for _, a := range []*Assistant{call.AssistantOverrides, call.Assistant} {
+			if a != nil && a.AnalysisPlan != nil {
+				plan = a.AnalysisPlan
+				break
+			}
+		}
+	}
+	if plan == nil {
+		return nil, fmt.Errorf("failed to run analysis: no analysis plan")
+	}
+
+	vars := map[string]string{
+		"transcript":   callTranscriptText(call),
+		"systemPrompt": callSystemPrompt(call),
+	}
+	analysis := &Analysis{}
+
+	if p := plan.SummaryPlan; p != nil {
+		defaults := []ModelMessage{{Role: "system", Content: defaultSummaryPrompt}, transcriptMessage}
+		summary, err := r.generate(ctx, p.Messages, defaults, vars, p.TimeoutSeconds)
+		if err != nil {
+			return nil, fmt.Errorf("failed to run summary plan: %w", err)
+		}
+		analysis.Summary = &summary
+	}
+
+	if p := plan.StructuredDataPlan; p != nil && p.Enabled {
+		data, err := r.structuredData(ctx, p, vars)
+		if err != nil {
+			return nil, fmt.Errorf("failed to run structured data plan: %w", err)
+		}
+		obj, ok := data.(map[string]any)
+		if !ok {
+			return nil, fmt.Errorf("failed to run structured data plan: result is not an object")
+		}
+		analysis.StructuredData = obj
+	}
+
+	if len(plan.StructuredDataMulti) > 0 {
+		results := map[string]any{}
+		for _, m := range plan.StructuredDataMulti {
+			if m.Plan == nil {
+				continue
+			}
+			data, err := r.structuredData(ctx, m.Plan, vars)
+			if err != nil {
+				return nil, fmt.Errorf("failed to run structured data plan %q: %w", m.Key, err)
+			}
+			results[m.Key] = data
+		}
+		b, err := json.Marshal(results)
+		if err != nil {
+			return nil, err
+		}
+		analysis.StructuredDataMulti = b
+	}
+
+	if p := plan.SuccessEvaluationPlan; p != nil && (p.Enabled == nil || *p.Enabled) {
+		rubric := p.EvaluationRubric()
+		vars["rubric"] = rubricDescriptions[rubric]
+		defaults := []ModelMessage{{Role: "system", Content: defaultSuccessEvaluationPrompt}, transcriptMessage, systemPromptMessage}
+		result, err := r.generate(ctx, p.Messages, defaults, vars, p.TimeoutSeconds)
+		if err != nil {
+			return nil, fmt.Errorf("failed to run success evaluation plan: %w", err)
+		}
+		analysis.SuccessEvaluation = result
+		// Vapi reports PassFail results as booleans
+		if rubric == RubricPassFail {
+			if passed, err := analysis.PassFail(); err == nil {
+				analysis.SuccessEvaluation = passed
+			}
+		}
+	}
+
+	return analysis, nil
+}
+
+// structuredData runs a structured data plan and validates the result
+// against the plan's schema
+func (r *AnalysisRunner) structuredData(ctx context.Context, p *StructuredDataPlan, vars map[string]string) (any, error) {
+	vars["schema"] = ""
+	var opts []minds.RequestOption
+	if p.Schema != nil {
+		schema, err := json.Marshal(p.Schema)
+		if err != nil {
+			return nil, err
+		}
+		vars["schema"] = string(schema)
+		opts = append(opts, minds.WithResponseSchema(minds.ResponseSchema{Name: "structured_data", Definition: *p.Schema}))
+	}
+
+	defaults := []ModelMessage{{Role: "system", Content: defaultStructuredDataPrompt}, transcriptMessage}
+	text, err := r.generate(ctx, p.Messages, defaults, vars, p.TimeoutSeconds, opts...)
+	if err != nil {
+		return nil, err
+	}
+	var data any
+	if err := json.Unmarshal([]byte(stripCodeFence(text)), &data); err != nil {
+		return nil, fmt.Errorf("result is not JSON: %w", err)
+	}
+	if p.Schema != nil {
+		if err := ValidateStructuredData(*p.Schema, data); err != nil {
+			return nil, err
+		}
+	}
+	return data, nil
+}
+
+// generate sends the plan's messages, or the defaults when it has none,
+// with the variables substituted and returns the text of the response
+func (r *AnalysisRunner) generate(ctx context.Context, messages, defaults []ModelMessage, vars map[string]string, timeoutSeconds *int, opts ...minds.RequestOption) (string, error) {
+	if len(messages) == 0 {
+		messages = defaults
+	}
+	if timeoutSeconds != nil && *timeoutSeconds > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, time.Duration(*timeoutSeconds)*time.Second)
+		defer cancel()
+	}
+
+	var msgs minds.Messages
+	for _, m := range messages {
+		content := analysisVariablePattern.ReplaceAllStringFunc(m.Content, func(match string) string {
+			name := analysisVariablePattern.FindStringSubmatch(match)[1]
+			if v, ok := vars[name]; ok {
+				return v
+			}
+			return match
+		})
+		msgs = append(msgs, minds.Message{Role: minds.Role(m.Role), Content: content})
+	}
+
+	resp, err := r.Provider.GenerateContent(ctx, minds.NewRequest(msgs, append(append([]minds.RequestOption{}, r.Options...), opts...)...))
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(resp.String()), nil
+}
+
+// callTranscriptText is the call's transcript as Vapi renders it, or one
+// built from its messages
+func callTranscriptText(call *Call) string {
+	if call.Artifact != nil && call.Artifact.Transcript != "" {
+		return call.Artifact.Transcript
+	}
+	return TranscriptFromCall(call).String()
+}
+
+// callSystemPrompt is the system message of the call's assistant, or the
+// system message recorded in the call
+func callSystemPrompt(call *Call) string {
+	for _, a := range []*Assistant{call.AssistantOverrides, call.Assistant} {
+		if a == nil || a.Model == nil {
+			continue
+		}
+		for _, m := range a.Model.Messages {
+			if m.Role == "system" {
+				return m.Content
+			}
+		}
+	}
+	for _, e := range TranscriptFromCall(call).Entries {
+		if e.Kind == EntrySystem {
+			return e.Text
+		}
+	}
+	return ""
+}
+
+// stripCodeFence removes a Markdown code fence around a model's JSON answer
+func stripCodeFence(s string) string {
+	s = strings.TrimSpace(s)
+	if !strings.HasPrefix(s, "```") {
+		return s
+	}
+	s = strings.TrimPrefix(s, "```")
+	if i := strings.IndexByte(s, '\n'); i >= 0 {
+		s = s[i+1:]
+	}
+	return strings.TrimSpace(strings.TrimSuffix(strings.TrimSpace(s), "```"))
+}
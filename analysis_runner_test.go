@@ -0,0 +1,105 @@
+package vapi
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/chriscow/minds"
+)
+
+type fakeResponse string
+
+func (r fakeResponse) String() string              { return string(r) }
+func (r fakeResponse) ToolCalls() []minds.ToolCall { return nil }
+
+// fakeProvider answers each request with the reply whose key appears in the
+// request's first message
+type fakeProvider struct {
+	replies  map[string]string
+	requests []minds.Request
+}
+
+func (p *fakeProvider) ModelName() string { return "fake" }
+func (p *fakeProvider) Close()            {}
+
+func (p *fakeProvider) GenerateContent(ctx context.Context, req minds.Request) (minds.Response, error) {
+	p.requests = append(p.requests, req)
+	for key, reply := range p.replies {
+		if strings.Contains(req.Messages[0].Content, key) {
+			return fakeResponse(reply), nil
+		}
+	}
+	return fakeResponse(""), nil
+}
+
+func TestAnalysisRunner(t *testing.T) {
+	report := loadReportFixture(t)
+	call := report.Call
+	call.Artifact = report.Artifact
+	call.Artifact.Transcript = "AI: Hi\nUser: Where is my order?"
+
+	schema, err := StructuredDataSchema[struct {
+		Topic string `json:"topic"`
+	}]()
+	if err != nil {
+		t.Fatal(err)
+	}
+	rubric := RubricNumericScale
+	plan := &AnalysisPlan{
+		SummaryPlan:        &SummaryPlan{},
+		StructuredDataPlan: &StructuredDataPlan{Enabled: true, Schema: schema},
+		StructuredDataMulti: []StructuredDataMulti{{Key: "sentiment", Plan: &StructuredDataPlan{
+			Messages: []ModelMessage{{Role: "system", Content: "Rate the sentiment as JSON."}, {Role: "user", Content: "{{ transcript }}"}},
+		}}},
+		SuccessEvaluationPlan: &SuccessEvaluationPlan{Rubric: &rubric},
+	}
+
+	provider := &fakeProvider{replies: map[string]string{
+		"note-taker":     "The caller asked about an order.",
+		"data extractor": "```json\n{\"topic\": \"order status\"}\n```",
+		"sentiment":      `{"mood": "calm"}`,
+		"call evaluator": "8",
+	}}
+	analysis, err := NewAnalysisRunner(provider, minds.WithModel("gpt-4o")).Run(context.Background(), call, plan)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if analysis.Summary == nil || *analysis.Summary != "The caller asked about an order." {
+		t.Errorf("Summary = %v", analysis.Summary)
+	}
+	if analysis.StructuredData["topic"] != "order status" {
+		t.Errorf("StructuredData = %v", analysis.StructuredData)
+	}
+	if got := string(analysis.StructuredDataMulti); got != `{"sentiment":{"mood":"calm"}}` {
+		t.Errorf("StructuredDataMulti = %s", got)
+	}
+	if score, err := analysis.NumericScore(); err != nil || score != 8 {
+		t.Errorf("NumericScore() = %d, %v", score, err)
+	}
+
+	if len(provider.requests) != 4 {
+		t.Fatalf("got %d requests, want 4", len(provider.requests))
+	}
+	for _, req := range provider.requests {
+		if req.Options.ModelName == nil || *req.Options.ModelName != "gpt-4o" {
+			t.Errorf("request options = %+v", req.Options)
+		}
+	}
+	if got := provider.requests[2].Messages[1].Content; got != call.Artifact.Transcript {
+		t.Errorf("{{ transcript }} = %q", got)
+	}
+	if provider.requests[1].Options.ResponseSchema == nil || !strings.Contains(provider.requests[1].Messages[0].Content, `"topic"`) {
+		t.Errorf("structured data request did not carry the schema: %+v", provider.requests[1])
+	}
+	evaluation := provider.requests[3].Messages
+	if !strings.Contains(evaluation[0].Content, "NumericScale: A scale of 1 to 10.") || !strings.Contains(evaluation[2].Content, "You are Alex.") {
+		t.Errorf("success evaluation messages = %+v", evaluation)
+	}
+
+	provider.replies = map[string]string{"data extractor": `{"topic": 3}`}
+	if _, err := NewAnalysisRunner(provider).Run(context.Background(), call, &AnalysisPlan{StructuredDataPlan: plan.StructuredDataPlan}); err == nil || !strings.Contains(err.Error(), "topic") {
+		t.Errorf("invalid structured data: err = %v", err)
+	}
+}
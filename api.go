@@ -0,0 +1,73 @@
+package vapi
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+)
+
+// apiBaseURL is the root of the Vapi REST API. Tests point it at a local server.
+var apiBaseURL = "https://api.vapi.ai"
+
+// apiRequest sends an authenticated request to the Vapi API. The payload, if
+// any, is sent as JSON and the response body is decoded into out when it is
+// not nil. what names the operation in error messages, e.g. "update tool".
+func apiRequest(ctx context.Context, method, path string, payload any, wantStatus int, out any, what string) error {
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+
+	var reqBody *bytes.Buffer
+	if payload != nil {
+		b, err := json.Marshal(payload)
+		if err != nil {
+			return err
+		}
+		reqBody = bytes.NewBuffer(b)
+	} else {
+		reqBody = &bytes.Buffer{}
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, apiBaseURL+path, reqBody)
+	if err != nil {
+		return fmt.Errorf("failed to create request to %s: %w", what, err)
+	}
+
+	apiKey := os.Getenv("VAPI_API_KEY")
+	if apiKey == "" {
+		return fmt.Errorf("VAPI_API_KEY not set")
+	}
+
+	if payload != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	req.Header.Set("Authorization", "Bearer "+apiKey)
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to http client failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	var body bytes.Buffer
+	_, err = body.ReadFrom(resp.Body)
+	if err != nil {
+		return fmt.Errorf("failed to read response body: %w", err)
+	}
+
+	if resp.StatusCode != wantStatus {
+		return fmt.Errorf("failed to %s. code: %d msg: %s", what, resp.StatusCode, body.String())
+	}
+
+	if out == nil {
+		return nil
+	}
+	if err := decodeResponse(body.Bytes(), out); err != nil {
+		return fmt.Errorf("failed to unmarshal response: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,359 @@
+package vapi
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"hash"
+	"io"
+	"mime"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// DefaultMaxArtifactSize is the largest file DownloadArtifacts accepts
+// unless WithMaxArtifactSize says otherwise
+const DefaultMaxArtifactSize int64 = 2 << 30
+
+// Kinds of artifact DownloadArtifacts writes
+const (
+	ArtifactRecording       = "recording"
+	ArtifactStereoRecording = "stereo-recording"
+	ArtifactVideoRecording  = "video-recording"
+	ArtifactPcap            = "pcap"
+	ArtifactTranscript      = "transcript"
+	ArtifactCall            = "call"
+)
+
+// DownloadedArtifact is a file written by DownloadArtifacts
+type DownloadedArtifact struct {
+	Kind        string
+	URL         string // empty for files generated from the call
+	Path        string
+	Size        int64
+	ContentType string
+	SHA256      string
+}
+
+// DownloadOption configures DownloadArtifacts
+type DownloadOption func(*downloadConfig)
+
+type downloadConfig struct {
+	client  *http.Client
+	maxSize int64
+}
+
+// WithDownloadClient sets the HTTP client used to fetch artifacts. The
+// default is http.DefaultClient.
+func WithDownloadClient(client *http.Client) DownloadOption {
+	return func(c *downloadConfig) { c.client = client }
+}
+
+// WithMaxArtifactSize limits the size of each downloaded file. Zero or less
+// removes the limit.
+func WithMaxArtifactSize(n int64) DownloadOption {
+	return func(c *downloadConfig) { c.maxSize = n }
+}
+
+// artifactFile describes one remote artifact and the content types it may
+// be served as
+type artifactFile struct {
+	kind       string
+	url        string
+	name       string
+	defaultExt string
+	types      []string
+}
+
+// DownloadArtifacts archives a call under dest/<call ID>:
+//
+//	recording.wav          Artifact.RecordingUrl
+//	stereo-recording.wav   Artifact.StereoRecordingUrl
+//	video-recording.mp4    Artifact.VideoRecordingUrl
+//	capture.pcap           Artifact.PcapUrl
+//	transcript.json        the call's Transcript
+//	call.json              the call itself
+//	SHA256SUMS             checksums of the files above, for sha256sum -c
+//
+// Extensions follow the URL when it has one. Files are streamed to a
+// ".part" file first; an interrupted download resumes from where it stopped
+// with a Range request, and files already downloaded are not fetched again.
+// Responses that are not audio, video or binary (an HTML error page, say)
+// are rejected, as are files larger than the size limit.
+func DownloadArtifacts(ctx context.Context, call *Call, dest string, opts ...DownloadOption) ([]DownloadedArtifact, error) {
+	cfg := downloadConfig{client: http.DefaultClient, maxSize: DefaultMaxArtifactSize}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	if call.ID == nil || *call.ID == "" {
+		return nil, fmt.Errorf("failed to download artifacts: call has no ID")
+	}
+	id := *call.ID
+	if id == "." || id == ".." || strings.ContainsAny(id, `/\`) {
+		return nil, fmt.Errorf("failed to download artifacts: invalid call ID %q", id)
+	}
+	dir := filepath.Join(dest, id)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+
+	var files []artifactFile
+	if a := call.Artifact; a != nil {
+		audio := []string{"audio/"}
+		files = []artifactFile{
+			{ArtifactRecording, a.RecordingUrl, "recording", ".wav", audio},
+			{ArtifactStereoRecording, a.StereoRecordingUrl, "stereo-recording", ".wav", audio},
+			{ArtifactVideoRecording, a.VideoRecordingUrl, "video-recording", ".mp4", []string{"video/"}},
+			{ArtifactPcap, a.PcapUrl, "capture", ".pcap", []string{"application/vnd.tcpdump.pcap", "application/x-pcap"}},
+		}
+	}
+
+	var downloaded []DownloadedArtifact
+	for _, f := range files {
+		if f.url == "" {
+			continue
+		}
+		d, err := downloadArtifact(ctx, cfg, dir, f)
+		if err != nil {
+			return downloaded, fmt.Errorf("failed to download %s: %w", f.kind, err)
+		}
+		downloaded = append(downloaded, d)
+	}
+
+	transcript := TranscriptFromCall(call)
+	var records []transcriptRecord
+	for _, e := range transcript.exported(ExportOptions{IncludeTools: true}) {
+		records = append(records, newTranscriptRecord(e))
+	}
+	if records == nil {
+		records = []transcriptRecord{}
+	}
+	for _, f := range []struct {
+		kind, name string
+		v          any
+	}{
+		{ArtifactTranscript, "transcript.json", records},
+		{ArtifactCall, "call.json", call},
+	} {
+		d, err := writeJSONArtifact(filepath.Join(dir, f.name), f.v)
+		if err != nil {
+			return downloaded, fmt.Errorf("failed to write %s: %w", f.name, err)
+		}
+		d.Kind = f.kind
+		downloaded = append(downloaded, d)
+	}
+
+	var sums strings.Builder
+	for _, d := range downloaded {
+		fmt.Fprintf(&sums, "%s  %s\n", d.SHA256, filepath.Base(d.Path))
+	}
+	if err := writeFileAtomic(filepath.Join(dir, "SHA256SUMS"), []byte(sums.String())); err != nil {
+		return downloaded, err
+	}
+	return downloaded, nil
+}
+
+// downloadArtifact fetches one file into dir, resuming a partial download
+func downloadArtifact(ctx context.Context, cfg downloadConfig, dir string, f artifactFile) (DownloadedArtifact, error) {
+	ext := path.Ext(urlPath(f.url))
+	if ext == "" {
+		ext = f.defaultExt
+	}
+	d := DownloadedArtifact{Kind: f.kind, URL: f.url, Path: filepath.Join(dir, f.name+ext)}
+
+	// Already downloaded on an earlier run
+	if info, err := os.Stat(d.Path); err == nil {
+		d.Size = info.Size()
+		d.SHA256, err = fileSHA256(d.Path)
+		return d, err
+	}
+
+	part := d.Path + ".part"
+	h := sha256.New()
+	var offset int64
+	if info, err := os.Stat(part); err == nil && info.Size() > 0 {
+		if err := hashFile(h, part); err != nil {
+			return d, err
+		}
+		offset = info.Size()
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, f.url, nil)
+	if err != nil {
+		return d, err
+	}
+	if offset > 0 {
+		req.Header.Set("Range", fmt.Sprintf("bytes=%d-", offset))
+	}
+	resp, err := cfg.client.Do(req)
+	if err != nil {
+		return d, err
+	}
+	defer resp.Body.Close()
+
+	flags := os.O_WRONLY | os.O_CREATE
+	switch {
+	case resp.StatusCode == http.StatusPartialContent && offset > 0:
+		start, total, ok := parseContentRange(resp.Header.Get("Content-Range"))
+		if !ok || start != offset {
+			return d, fmt.Errorf("unexpected Content-Range %q resuming at %d", resp.Header.Get("Content-Range"), offset)
+		}
+		if cfg.maxSize > 0 && total > cfg.maxSize {
+			return d, fmt.Errorf("file is %d bytes, limit is %d", total, cfg.maxSize)
+		}
+		flags |= os.O_APPEND
+	case resp.StatusCode == http.StatusRequestedRangeNotSatisfiable && offset > 0:
+		// The part file may already hold the whole file
+		if _, total, ok := parseContentRange(resp.Header.Get("Content-Range")); !ok || total != offset {
+			return d, fmt.Errorf("failed to resume at %d. code: %d", offset, resp.StatusCode)
+		}
+		d.Size, d.SHA256 = offset, hex.EncodeToString(h.Sum(nil))
+		return d, os.Rename(part, d.Path)
+	case resp.StatusCode == http.StatusOK:
+		// No resume support, or nothing to resume: start over
+		offset = 0
+		h.Reset()
+		flags |= os.O_TRUNC
+		if cfg.maxSize > 0 && resp.ContentLength > cfg.maxSize {
+			return d, fmt.Errorf("file is %d bytes, limit is %d", resp.ContentLength, cfg.maxSize)
+		}
+	default:
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return d, fmt.Errorf("failed to fetch. code: %d msg: %s", resp.StatusCode, body)
+	}
+
+	d.ContentType = resp.Header.Get("Content-Type")
+	if err := checkContentType(d.ContentType, f.types); err != nil {
+		return d, err
+	}
+
+	out, err := os.OpenFile(part, flags, 0o644)
+	if err != nil {
+		return d, err
+	}
+	body := io.Reader(resp.Body)
+	if cfg.maxSize > 0 {
+		body = io.LimitReader(resp.Body, cfg.maxSize-offset+1)
+	}
+	n, err := io.Copy(io.MultiWriter(out, h), body)
+	if cerr := out.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		// Keep the part file so the next run can resume
+		return d, err
+	}
+	if cfg.maxSize > 0 && offset+n > cfg.maxSize {
+		os.Remove(part)
+		return d, fmt.Errorf("file is larger than the limit of %d bytes", cfg.maxSize)
+	}
+
+	d.Size, d.SHA256 = offset+n, hex.EncodeToString(h.Sum(nil))
+	return d, os.Rename(part, d.Path)
+}
+
+// checkContentType accepts the expected types and generic binary types
+func checkContentType(contentType string, allowed []string) error {
+	if contentType == "" {
+		return nil
+	}
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return fmt.Errorf("invalid content type %q", contentType)
+	}
+	if mediaType == "application/octet-stream" || mediaType == "binary/octet-stream" {
+		return nil
+	}
+	for _, a := range allowed {
+		if mediaType == a || (strings.HasSuffix(a, "/") && strings.HasPrefix(mediaType, a)) {
+			return nil
+		}
+	}
+	return fmt.Errorf("unexpected content type %q", contentType)
+}
+
+// parseContentRange parses "bytes start-end/total". total is -1 when the
+// server does not know it.
+func parseContentRange(s string) (start, total int64, ok bool) {
+	if !strings.HasPrefix(s, "bytes ") {
+		return 0, 0, false
+	}
+	s = strings.TrimPrefix(s, "bytes ")
+	rng, size, found := strings.Cut(s, "/")
+	if !found {
+		return 0, 0, false
+	}
+	total = -1
+	if size != "*" {
+		var err error
+		if total, err = strconv.ParseInt(size, 10, 64); err != nil {
+			return 0, 0, false
+		}
+	}
+	if rng == "*" {
+		return 0, total, true
+	}
+	first, _, found := strings.Cut(rng, "-")
+	if !found {
+		return 0, 0, false
+	}
+	start, err := strconv.ParseInt(first, 10, 64)
+	return start, total, err == nil
+}
+
+// urlPath returns the path of a URL without its query
+func urlPath(u string) string {
+	if i := strings.IndexAny(u, "?#"); i >= 0 {
+		u = u[:i]
+	}
+	return u
+}
+
+func writeJSONArtifact(file string, v any) (DownloadedArtifact, error) {
+	b, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return DownloadedArtifact{}, err
+	}
+	b = append(b, '\n')
+	if err := writeFileAtomic(file, b); err != nil {
+		return DownloadedArtifact{}, err
+	}
+	sum := sha256.Sum256(b)
+	return DownloadedArtifact{Path: file, Size: int64(len(b)), ContentType: "application/json", SHA256: hex.EncodeToString(sum[:])}, nil
+}
+
+// writeFileAtomic writes data to a temporary file and renames it into place
+func writeFileAtomic(file string, data []byte) error {
+	tmp := file + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, file)
+}
+
+func fileSHA256(file string) (string, error) {
+	h := sha256.New()
+	if err := hashFile(h, file); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+func hashFile(h hash.Hash, file string) error {
+	f, err := os.Open(file)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	if _, err := io.Copy(h, f); err != nil {
+		return fmt.Errorf("failed to read %s: %w", file, err)
+	}
+	return nil
+}
@@ -0,0 +1,80 @@
+package vapi
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDownloadArtifacts(t *testing.T) {
+	recording := bytes.Repeat([]byte("RIFF-audio-"), 1000)
+	var ranges []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ranges = append(ranges, r.Header.Get("Range"))
+		switch r.URL.Path {
+		case "/recording.wav":
+			w.Header().Set("Content-Type", "audio/wav")
+			http.ServeContent(w, r, "recording.wav", time.Time{}, bytes.NewReader(recording))
+		case "/expired.mp4":
+			w.Header().Set("Content-Type", "text/html")
+			w.Write([]byte("<html>expired</html>"))
+		}
+	}))
+	defer srv.Close()
+
+	report := loadReportFixture(t)
+	call := report.Call
+	call.Artifact = report.Artifact
+	call.Artifact.RecordingUrl = srv.URL + "/recording.wav?sig=abc"
+
+	// A previous run was interrupted half way
+	dest := t.TempDir()
+	os.MkdirAll(filepath.Join(dest, "call-1"), 0o755)
+	os.WriteFile(filepath.Join(dest, "call-1", "recording.wav.part"), recording[:4000], 0o644)
+
+	files, err := DownloadArtifacts(context.Background(), call, dest)
+	if err != nil {
+		t.Fatalf("DownloadArtifacts() error = %v", err)
+	}
+	if len(ranges) != 1 || ranges[0] != "bytes=4000-" {
+		t.Errorf("Range headers = %q, want a resume from 4000", ranges)
+	}
+
+	got, _ := os.ReadFile(filepath.Join(dest, "call-1", "recording.wav"))
+	sum := sha256.Sum256(recording)
+	if !bytes.Equal(got, recording) || files[0].SHA256 != hex.EncodeToString(sum[:]) {
+		t.Errorf("recording = %d bytes, sha %s; want %d bytes", len(got), files[0].SHA256, len(recording))
+	}
+	for _, name := range []string{"transcript.json", "call.json", "SHA256SUMS"} {
+		if _, err := os.Stat(filepath.Join(dest, "call-1", name)); err != nil {
+			t.Errorf("missing %s: %v", name, err)
+		}
+	}
+	sums, _ := os.ReadFile(filepath.Join(dest, "call-1", "SHA256SUMS"))
+	if !strings.Contains(string(sums), hex.EncodeToString(sum[:])+"  recording.wav\n") {
+		t.Errorf("SHA256SUMS =\n%s", sums)
+	}
+
+	// A second run does not download again
+	if _, err := DownloadArtifacts(context.Background(), call, dest); err != nil || len(ranges) != 1 {
+		t.Errorf("second DownloadArtifacts() = %v after %d requests", err, len(ranges))
+	}
+
+	call.Artifact.VideoRecordingUrl = srv.URL + "/expired.mp4"
+	if _, err := DownloadArtifacts(context.Background(), call, dest); err == nil || !strings.Contains(err.Error(), "content type") {
+		t.Errorf("DownloadArtifacts() with HTML response error = %v", err)
+	}
+
+	call.Artifact.VideoRecordingUrl = ""
+	if _, err := DownloadArtifacts(context.Background(), call, t.TempDir(), WithMaxArtifactSize(1000)); err == nil || !strings.Contains(err.Error(), "limit") {
+		t.Errorf("DownloadArtifacts() over the size limit error = %v", err)
+	}
+}
@@ -1,7 +1,6 @@
 package vapi
 
 import (
-	"bytes"
 	"context"
 	"encoding/json"
 	"fmt"
@@ -38,6 +37,11 @@ type Assistant struct {
 	BackgroundDenoisingEnabled   *bool                  `json:"backgroundDenoisingEnabled,omitempty"`
 	ModelOutputInMessagesEnabled *bool                  `json:"modelOutputInMessagesEnabled,omitempty"`
 	VariableValues               map[string]any         `json:"variableValues,omitempty"`
+	Metadata                     map[string]any         `json:"metadata,omitempty"`
+
+	// Extra holds JSON fields this struct does not model, so they survive
+	// a round trip. See UnknownFields.
+	Extra map[string]json.RawMessage `json:"-"`
 }
 
 // TranscriberConfig contains settings for speech-to-text
@@ -45,6 +49,8 @@ type TranscriberConfig struct {
 	Model    string `json:"model"`
 	Language string `json:"language"`
 	Provider string `json:"provider"`
+
+	Extra map[string]json.RawMessage `json:"-"`
 }
 
 // VoicemailConfig contains settings for voicemail detection
@@ -56,6 +62,8 @@ type VoicemailConfig struct {
 	MachineDetectionSpeechThreshold    int      `json:"machineDetectionSpeechThreshold"`
 	MachineDetectionSpeechEndThreshold int      `json:"machineDetectionSpeechEndThreshold"`
 	MachineDetectionSilenceTimeout     int      `json:"machineDetectionSilenceTimeout"`
+
+	Extra map[string]json.RawMessage `json:"-"`
 }
 
 // TranscriptionEndpointingPlan contains timing settings for transcription
@@ -63,16 +71,22 @@ type TranscriptionEndpointingPlan struct {
 	OnPunctuationSeconds   float64 `json:"onPunctuationSeconds"`
 	OnNoPunctuationSeconds float64 `json:"onNoPunctuationSeconds"`
 	OnNumberSeconds        float64 `json:"onNumberSeconds"`
+
+	Extra map[string]json.RawMessage `json:"-"`
 }
 
 // SpeakingPlan contains settings for speech timing
 type SpeakingPlan struct {
 	WaitSeconds float64 `json:"waitSeconds"`
+
+	Extra map[string]json.RawMessage `json:"-"`
 }
 
 // SchemaProperty represents a property in the structured data schema
 type SchemaProperty struct {
 	Type string `json:"type"`
+
+	Extra map[string]json.RawMessage `json:"-"`
 }
 
 // AnalysisPlan contains settings for call analysis
@@ -80,25 +94,49 @@ type AnalysisPlan struct {
 	SummaryPlan         *SummaryPlan          `json:"summaryPlan,omitempty"`
 	StructuredDataPlan  *StructuredDataPlan   `json:"structuredDataPlan,omitempty"`
 	StructuredDataMulti []StructuredDataMulti `json:"structuredDataMulti,omitempty"`
+
+	SuccessEvaluationPlan *SuccessEvaluationPlan `json:"successEvaluationPlan,omitempty"`
+
+	Extra map[string]json.RawMessage `json:"-"`
 }
 
+// SummaryPlan contains settings for the call summary. Messages use the
+// OpenAI format and may reference {{transcript}}.
 type SummaryPlan struct {
-	Messages       []Message `json:"messages"`
-	TimeoutSeconds *int      `json:"timeoutSeconds,omitempty"` // defaults to 5 seconds
+	Messages       []ModelMessage `json:"messages"`
+	TimeoutSeconds *int           `json:"timeoutSeconds,omitempty"` // defaults to 5 seconds
+
+	Extra map[string]json.RawMessage `json:"-"`
 }
 
 type StructuredDataPlan struct {
-	Messages       []Message         `json:"messages"`
+	Messages       []ModelMessage    `json:"messages"`
 	Enabled        bool              `json:"enabled"` // defaults to false
 	Schema         *minds.Definition `json:"schema,omitempty"`
 	TimeoutSeconds *int              `json:"timeoutSeconds,omitempty"` // defaults to 5 seconds
+
+	Extra map[string]json.RawMessage `json:"-"`
 }
 
 type StructuredDataMulti struct {
 	Key  string              `json:"key"`
 	Plan *StructuredDataPlan `json:"plan,omitempty"`
+
+	Extra map[string]json.RawMessage `json:"-"`
 }
 
+// Success evaluation rubrics
+const (
+	RubricNumericScale     = "NumericScale"
+	RubricDescriptiveScale = "DescriptiveScale"
+	RubricChecklist        = "Checklist"
+	RubricMatrix           = "Matrix"
+	RubricPercentageScale  = "PercentageScale"
+	RubricLikertScale      = "LikertScale"
+	RubricAutomaticRubric  = "AutomaticRubric"
+	RubricPassFail         = "PassFail"
+)
+
 type SuccessEvaluationPlan struct {
 	// 	Rubric (enum) options include:
 	// 		‘NumericScale’: A scale of 1 to 10.
@@ -131,9 +169,11 @@ type SuccessEvaluationPlan struct {
 	// {{systemPrompt}}: the system prompt of the call from
 	// assistant.model.messages[type=system].content- {{rubric}}: the rubric of the
 	// success evaluation from successEvaluationPlan.rubric
-	Messages       []Message `json:"messages,omitempty"`
-	Enabled        *bool     `json:"enabled,omitempty"`        // defaults to true
-	TimeoutSeconds *int      `json:"timeoutSeconds,omitempty"` // defaults to 5 seconds
+	Messages       []ModelMessage `json:"messages,omitempty"`
+	Enabled        *bool          `json:"enabled,omitempty"`        // defaults to true
+	TimeoutSeconds *int           `json:"timeoutSeconds,omitempty"` // defaults to 5 seconds
+
+	Extra map[string]json.RawMessage `json:"-"`
 }
 
 var DefaultElevenLabsVoiceConfig = ElevenLabsVoiceConfig{
@@ -158,92 +198,85 @@ var DefaultTranscriber = TranscriberConfig{
 	Provider: "deepgram",
 }
 
+// DefaultAssistant returns an assistant built from the package defaults,
+// with prompt as its system prompt and webhook as its server URL when set.
+// The defaults are copied, so the result can be modified freely.
 func DefaultAssistant(agentName, prompt, firstMessage, webhook, voicemailMessage, endCallMessage string) (*Assistant, error) {
-	// VoicemailConfig{
-	// 	Provider: "twilio",
-	// 	VoicemailDetectionTypes: []string{
-	// 		"machine_start",
-	// 		"machine_end_beep",
-	// 		"machine_end_silence",
-	// 		"machine_end_other",
-	// 	},
-	// 	Enabled:                            true,
-	// 	MachineDetectionTimeout:            25,
-	// 	MachineDetectionSpeechThreshold:    2500,
-	// 	MachineDetectionSpeechEndThreshold: 2000,
-	// 	MachineDetectionSilenceTimeout:     2000,
-	// },
-
-	req := &Assistant{
-		Name:                   &agentName,
-		Voice:                  &DefaultElevenLabsVoiceConfig,
-		Model:                  &DefaultModelConfig,
-		Transcriber:            &DefaultTranscriber,
-		FirstMessage:           &firstMessage,
-		ClientMessages:         []string{},
-		ServerMessages:         []string{"end-of-call-report", "function-call", "tool-calls"},
-		Server:                 nil,
-		EndCallFunctionEnabled: true,
-		EndCallMessage:         &endCallMessage,
-		EndCallPhrases:         []string{"goodbye"},
-		VoicemailDetection:     nil,
-		VoicemailMessage:       &voicemailMessage,
-		AnalysisPlan:           nil,
-		// SilenceTimeoutSeconds:        30,
-		// MaxDurationSeconds:           180,
-		// BackgroundSound:              "off",
-		// BackchannelingEnabled:        false,
-		// BackgroundDenoisingEnabled:   false,
-		// ModelOutputInMessagesEnabled: false,
+	b := NewAssistant(agentName).
+		WithFirstMessage(firstMessage).
+		WithEndCallMessage(endCallMessage).
+		WithVoicemailMessage(voicemailMessage)
+	if prompt != "" {
+		b.WithPrompt(prompt)
 	}
-
-	return req, nil
+	if webhook != "" {
+		b.WithServer(webhook)
+	}
+	return b.Build()
 }
 
 // GetAssistant retrieves an assistant by its ID and saves the raw JSON response to a file
 func GetAssistant(ctx context.Context, id string) (*Assistant, error) {
-	if ctx.Err() != nil {
-		return nil, ctx.Err()
-	}
-
-	req, err := http.NewRequest("GET", fmt.Sprintf("https://api.vapi.ai/assistant/%s", id), nil)
+	result, raw, err := getAssistant(ctx, id)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create request for assistant: %w", err)
+		return nil, err
 	}
 
-	apiKey := os.Getenv("VAPI_API_KEY")
-	if apiKey == "" {
-		return nil, fmt.Errorf("VAPI_API_KEY not set")
+	// Save the raw JSON response to a file for examination
+	filename := fmt.Sprintf("assistant-%s-response.json", id)
+	if err := os.WriteFile(filename, raw, 0644); err != nil {
+		return nil, fmt.Errorf("failed to save response to file: %w", err)
 	}
 
-	req.Header.Set("Authorization", "Bearer "+apiKey)
+	return result, nil
+}
 
-	resp, err := http.DefaultClient.Do(req)
-	if err != nil {
-		return nil, fmt.Errorf("failed to http client failed: %w", err)
+// getAssistant retrieves an assistant along with the raw response
+func getAssistant(ctx context.Context, id string) (*Assistant, json.RawMessage, error) {
+	var raw json.RawMessage
+	if err := apiRequest(ctx, http.MethodGet, "/assistant/"+id, nil, http.StatusOK, &raw, "get assistant"); err != nil {
+		return nil, nil, err
 	}
-	defer resp.Body.Close()
 
-	var body bytes.Buffer
-	_, err = body.ReadFrom(resp.Body)
-	if err != nil {
-		return nil, fmt.Errorf("failed to read response body: %w", err)
+	var result Assistant
+	if err := decodeResponse(raw, &result); err != nil {
+		return nil, nil, fmt.Errorf("failed to unmarshal response: %w", err)
 	}
+	return &result, raw, nil
+}
 
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("failed to get assistant. code: %d msg: %s", resp.StatusCode, body.String())
-	}
+// CreateAssistant creates a new assistant with the given configuration
+func CreateAssistant(ctx context.Context, assistant *Assistant) (*Assistant, error) {
+	return createAssistant(ctx, assistant)
+}
 
-	// Save the raw JSON response to a file for examination
-	filename := fmt.Sprintf("assistant-%s-response.json", id)
-	if err := os.WriteFile(filename, body.Bytes(), 0644); err != nil {
-		return nil, fmt.Errorf("failed to save response to file: %w", err)
-	}
+// UpdateAssistant patches the assistant with the given ID. Server-assigned
+// fields such as the ID are not sent.
+func UpdateAssistant(ctx context.Context, id string, assistant *Assistant) (*Assistant, error) {
+	patch := *assistant
+	patch.ID = nil
+	return updateAssistant(ctx, id, &patch)
+}
 
+// createAssistant and updateAssistant send any payload, such as the spec of
+// a manifest, so fields the caller did not set are left out
+func createAssistant(ctx context.Context, payload any) (*Assistant, error) {
 	var result Assistant
-	if err := json.Unmarshal(body.Bytes(), &result); err != nil {
-		return nil, fmt.Errorf("failed to unmarshal response: %w", err)
+	if err := apiRequest(ctx, http.MethodPost, "/assistant", payload, http.StatusCreated, &result, "create assistant"); err != nil {
+		return nil, err
 	}
+	return &result, nil
+}
 
+func updateAssistant(ctx context.Context, id string, payload any) (*Assistant, error) {
+	var result Assistant
+	if err := apiRequest(ctx, http.MethodPatch, "/assistant/"+id, payload, http.StatusOK, &result, "update assistant"); err != nil {
+		return nil, err
+	}
 	return &result, nil
 }
+
+// DeleteAssistant deletes the assistant with the given ID
+func DeleteAssistant(ctx context.Context, id string) error {
+	return apiRequest(ctx, http.MethodDelete, "/assistant/"+id, nil, http.StatusOK, nil, "delete assistant")
+}
@@ -83,3 +83,150 @@ func TestGetAssistant_Integration(t *testing.T) {
 	// Log that the JSON was saved to a file for examination
 	t.Logf("Raw JSON response saved to assistant-%s-response.json", assistantID)
 }
+
+func TestDiffAssistants(t *testing.T) {
+	name := "Alex"
+	first, otherFirst := "Hi", "Hello"
+	a := &Assistant{Name: &name, FirstMessage: &first, Model: &ModelConfig{Provider: "openai", Temperature: 0.7}}
+	b := &Assistant{Name: &name, FirstMessage: &otherFirst, Model: &ModelConfig{Provider: "openai", Temperature: 1}}
+
+	changes, err := DiffAssistants(a, b)
+	if err != nil {
+		t.Fatalf("DiffAssistants() error = %v", err)
+	}
+	if len(changes) != 2 {
+		t.Fatalf("got %d changes, want 2: %v", len(changes), changes)
+	}
+	if changes[0].Path != "firstMessage" || changes[0].Old != "Hi" || changes[0].New != "Hello" {
+		t.Errorf("changes[0] = %v", changes[0])
+	}
+	if changes[1].Path != "model.temperature" || changes[1].Old != 0.7 || changes[1].New != 1.0 {
+		t.Errorf("changes[1] = %v", changes[1])
+	}
+}
+
+func TestMergeAssistant(t *testing.T) {
+	first := "Hi {{name}}, calling about order {{ order.id }} from {{company}}."
+	base := &Assistant{
+		FirstMessage: &first,
+		Voice:        &ElevenLabsVoiceConfig{Provider: "11labs", VoiceID: "voice-1", Model: "eleven_flash_v2_5", Stability: 0.5},
+		Model: &ModelConfig{
+			Provider: "openai",
+			Messages: []ModelMessage{{Role: "system", Content: "You are helping {{name}}."}},
+		},
+		VariableValues: map[string]any{"company": "Acme"},
+	}
+	overrides := &Assistant{
+		Voice:          &ElevenLabsVoiceConfig{Stability: 0.8},
+		VariableValues: map[string]any{"name": "Pat", "order": map[string]any{"id": 42}},
+	}
+
+	got, err := MergeAssistant(base, overrides)
+	if err != nil {
+		t.Fatalf("MergeAssistant() error = %v", err)
+	}
+
+	if got.Voice.VoiceID != "voice-1" || got.Voice.Stability != 0.8 {
+		t.Errorf("voice = %+v, want voice-1 with stability 0.8", got.Voice)
+	}
+	if want := "Hi Pat, calling about order 42 from Acme."; *got.FirstMessage != want {
+		t.Errorf("FirstMessage = %q, want %q", *got.FirstMessage, want)
+	}
+	if want := "You are helping Pat."; got.Model.Messages[0].Content != want {
+		t.Errorf("system message = %q, want %q", got.Model.Messages[0].Content, want)
+	}
+	if *base.FirstMessage != first {
+		t.Error("MergeAssistant modified base")
+	}
+	// Pointer fields can be switched off explicitly
+	on, off := true, false
+	base.BackgroundDenoisingEnabled = &on
+	got, err = MergeAssistant(base, &Assistant{BackgroundDenoisingEnabled: &off})
+	if err != nil {
+		t.Fatalf("MergeAssistant() error = %v", err)
+	}
+	if got.BackgroundDenoisingEnabled == nil || *got.BackgroundDenoisingEnabled {
+		t.Errorf("BackgroundDenoisingEnabled = %v, want false", got.BackgroundDenoisingEnabled)
+	}
+	if got.Voice.VoiceID != "voice-1" || got.Model.Provider != "openai" {
+		t.Errorf("unset overrides changed the base: voice %+v, model %+v", got.Voice, got.Model)
+	}
+}
+
+func TestSubstituteVariables(t *testing.T) {
+	vars := map[string]any{"name": "pat", "order": map[string]any{"id": 42}}
+	tests := map[string]string{
+		"Hi {{name}}, order {{ order.id }}.": "Hi pat, order 42.",
+		"Hi {{name | upcase}}.":              "Hi {{name | upcase}}.",
+		"Call {{customer.number}}.":          "Call {{customer.number}}.",
+	}
+	for in, want := range tests {
+		if got := SubstituteVariables(in, vars); got != want {
+			t.Errorf("SubstituteVariables(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestAssistantBuilder_IndependentCopies(t *testing.T) {
+	b := NewAssistant("Alex").WithPrompt("You are Alex.").WithTools(Tool{Type: "endCall"})
+
+	a1, err := b.Build()
+	if err != nil {
+		t.Fatalf("Build() error = %v", err)
+	}
+	a2, err := b.Build()
+	if err != nil {
+		t.Fatalf("Build() error = %v", err)
+	}
+
+	a1.Voice.Stability = 0.1
+	a1.Model.Messages[0].Content = "changed"
+	if a2.Voice.Stability == 0.1 || a2.Model.Messages[0].Content == "changed" {
+		t.Error("assistants built from the same builder share state")
+	}
+	if DefaultElevenLabsVoiceConfig.Stability == 0.1 {
+		t.Error("modifying an assistant changed the package defaults")
+	}
+
+	// Builders never share the default model's slices
+	defer func(m ModelConfig) { DefaultModelConfig = m }(DefaultModelConfig)
+	DefaultModelConfig.ToolIDs = make([]string, 0, 4)
+	b1 := NewAssistant("One").WithToolIDs("tool-1")
+	NewAssistant("Two").WithToolIDs("tool-2")
+	if a, err := b1.Build(); err != nil || a.Model.ToolIDs[0] != "tool-1" {
+		t.Errorf("Build() tool IDs = %v, %v, want [tool-1]", a.Model.ToolIDs, err)
+	}
+
+	if _, err := NewAssistant("Bad").WithModel(ModelConfig{Provider: "openai", Model: "gpt-4o", Temperature: 5}).Build(); err == nil {
+		t.Error("Build() expected validation error for temperature 5")
+	}
+}
+
+func TestDefaultAssistant(t *testing.T) {
+	a, err := DefaultAssistant("Alex", "You are Alex.", "Hi!", "https://example.com/hook", "Call back.", "Bye.")
+	if err != nil {
+		t.Fatalf("DefaultAssistant() error = %v", err)
+	}
+	if len(a.Model.Messages) != 1 || a.Model.Messages[0].Content != "You are Alex." {
+		t.Errorf("messages = %+v, want the prompt", a.Model.Messages)
+	}
+	if a.Server == nil || a.Server.URL != "https://example.com/hook" {
+		t.Errorf("Server = %+v, want the webhook", a.Server)
+	}
+}
+
+func TestCallBuilder(t *testing.T) {
+	call, err := NewOutboundCall("pn-1", Customer{Number: "+14155550123"}).WithAssistantID("asst-1").Build()
+	if err != nil {
+		t.Fatalf("Build() error = %v", err)
+	}
+	if *call.PhoneNumberID != "pn-1" || *call.AssistantID != "asst-1" || call.Customer.Number != "+14155550123" {
+		t.Errorf("unexpected call: %+v", call)
+	}
+
+	// Without an assistant the phone number's own assistant takes the call
+	call, err = NewOutboundCall("pn-1", Customer{Number: "+14155550123"}).Build()
+	if err != nil || call.AssistantID != nil || call.Assistant != nil {
+		t.Errorf("Build() without an assistant = %+v, %v", call, err)
+	}
+}
@@ -0,0 +1,499 @@
+package vapi
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Scopes a budget limit applies to
+type BudgetScope string
+
+const (
+	BudgetOrg       BudgetScope = "org"
+	BudgetAssistant BudgetScope = "assistant"
+	BudgetCampaign  BudgetScope = "campaign"
+)
+
+// MetadataCampaign is the assistant metadata key naming the campaign a call
+// belongs to, for BudgetCampaign limits
+const MetadataCampaign = "campaign"
+
+// BudgetEach as a BudgetLimit key applies the limit to each org, assistant
+// or campaign separately
+const BudgetEach = "*"
+
+// DefaultCostPerMinute is the rate used to estimate the cost of a call
+// before any calls have been recorded
+const DefaultCostPerMinute = 0.15
+
+// defaultMaxDurationSeconds is Vapi's limit on the length of a call when the
+// assistant does not set one
+const defaultMaxDurationSeconds = 600
+
+// defaultReservationGrace is how long after its maximum duration a call
+// still counts as in flight when its cost is never recorded
+const defaultReservationGrace = 15 * time.Minute
+
+// BudgetMode is what a BudgetGuard does with a call that would exceed a cap
+type BudgetMode int
+
+const (
+	// BudgetReject fails CreateCall with a *BudgetExceededError
+	BudgetReject BudgetMode = iota
+	// BudgetQueue blocks CreateCall until running calls finish and free up
+	// enough budget, or until its context is done
+	BudgetQueue
+)
+
+// BudgetLimit caps the spend of a scope per calendar day and month. A zero
+// cap is no cap.
+type BudgetLimit struct {
+	Scope BudgetScope
+	// Key is the org ID, assistant ID or campaign the limit applies to.
+	// Empty caps all calls combined and BudgetEach caps each one separately.
+	Key     string
+	Daily   float64
+	Monthly float64
+}
+
+// BudgetExceededError is returned by CreateCall when placing the call could
+// take spend over a cap
+type BudgetExceededError struct {
+	Limit  BudgetLimit
+	Key    string // the org, assistant or campaign that hit the limit
+	Period string // "daily" or "monthly"
+	Cap    float64
+
+	Spent    float64 // cost of finished calls in the period
+	InFlight float64 // estimated cost of calls still running
+	Estimate float64 // estimated cost of the new call
+}
+
+func (e *BudgetExceededError) Error() string {
+	who := string(e.Limit.Scope)
+	if e.Key != "" {
+		who += " " + e.Key
+	}
+	return fmt.Sprintf("%s %s budget of $%.2f exceeded: $%.2f spent, $%.2f in flight, call estimated at $%.2f",
+		who, e.Period, e.Cap, e.Spent, e.InFlight, e.Estimate)
+}
+
+// BudgetGuard tracks call spend and stops CreateCall from placing calls that
+// could exceed a daily or monthly cap. Install one with SetBudgetGuard:
+//
+//	guard := vapi.NewBudgetGuard(vapi.BudgetReject,
+//		vapi.BudgetLimit{Scope: vapi.BudgetOrg, Daily: 200},
+//		vapi.BudgetLimit{Scope: vapi.BudgetCampaign, Key: vapi.BudgetEach, Daily: 50},
+//	)
+//	vapi.SetBudgetGuard(guard)
+//
+// A call in flight counts against the caps at its worst case: the
+// assistant's MaxDurationSeconds at the historical cost per minute. Its
+// estimate is replaced by the actual cost once the call is recorded, either
+// by passing the end-of-call report to RecordReport or by polling it with
+// GetCall, which records ended calls automatically.
+type BudgetGuard struct {
+	Limits []BudgetLimit
+	Mode   BudgetMode
+
+	// Location sets the time zone days and months start in. The default is
+	// UTC.
+	Location *time.Location
+	// CostPerMinute is used for estimates until calls have been recorded.
+	// The default is DefaultCostPerMinute.
+	CostPerMinute float64
+
+	// OrgID is the org the API key belongs to. Call requests do not carry
+	// an org ID, so BudgetOrg limits only apply to calls placed through
+	// CreateCall when it is set.
+	OrgID string
+
+	// ReservationTTL is how long a placed call counts as in flight if its
+	// cost is never recorded. The default is the call's maximum duration
+	// plus 15 minutes.
+	ReservationTTL time.Duration
+
+	mu       sync.Mutex
+	changed  chan struct{} // closed whenever spend or calls in flight change
+	spend    map[budgetBucket]float64
+	history  map[string]*costHistory // by assistant, "" for all calls
+	inFlight map[*budgetReservation]bool
+	byCall   map[string]*budgetReservation
+	recorded map[string]bool
+	now      func() time.Time
+}
+
+// budgetKey identifies an org, assistant or campaign. The zero key stands
+// for all calls.
+type budgetKey struct {
+	scope BudgetScope
+	value string
+}
+
+type budgetBucket struct {
+	key    budgetKey
+	period string
+}
+
+type budgetReservation struct {
+	keys     []budgetKey
+	estimate float64
+	expires  time.Time
+	callID   string
+}
+
+type costHistory struct {
+	cost, minutes float64
+}
+
+// NewBudgetGuard returns a guard enforcing limits
+func NewBudgetGuard(mode BudgetMode, limits ...BudgetLimit) *BudgetGuard {
+	return &BudgetGuard{Limits: limits, Mode: mode}
+}
+
+// init sets up the state of a guard built as a struct literal. The caller
+// holds g.mu.
+func (g *BudgetGuard) init() {
+	if g.changed == nil {
+		g.changed = make(chan struct{})
+	}
+	if g.spend == nil {
+		g.spend = map[budgetBucket]float64{}
+		g.history = map[string]*costHistory{}
+		g.inFlight = map[*budgetReservation]bool{}
+		g.byCall = map[string]*budgetReservation{}
+		g.recorded = map[string]bool{}
+	}
+	if g.now == nil {
+		g.now = time.Now
+	}
+}
+
+var (
+	budgetGuardMu sync.RWMutex
+	budgetGuard   *BudgetGuard
+)
+
+// SetBudgetGuard makes CreateCall and GetCall go through g. Passing nil
+// removes the guard.
+func SetBudgetGuard(g *BudgetGuard) {
+	budgetGuardMu.Lock()
+	defer budgetGuardMu.Unlock()
+	budgetGuard = g
+}
+
+func currentBudgetGuard() *BudgetGuard {
+	budgetGuardMu.RLock()
+	defer budgetGuardMu.RUnlock()
+	return budgetGuard
+}
+
+// RecordReport records the cost of a call from its end-of-call report.
+// Reports without a cost are ignored.
+func (g *BudgetGuard) RecordReport(report *EndOfCallReport) {
+	if g == nil || report == nil {
+		return
+	}
+	var call Call
+	if report.Call != nil {
+		call = *report.Call
+	}
+	if call.Assistant == nil {
+		call.Assistant = report.Assistant
+	}
+	call.Cost, call.Costs = report.Cost, report.Costs
+	if report.StartedAt != nil {
+		call.StartedAt = report.StartedAt
+	}
+	if report.EndedAt != nil {
+		call.EndedAt = report.EndedAt
+	}
+	g.record(&call)
+}
+
+// RecordCall records the cost of a polled call. Calls that have not ended
+// or have no cost yet are ignored, and each call is only counted once.
+func (g *BudgetGuard) RecordCall(call *Call) {
+	if g == nil || call == nil {
+		return
+	}
+	if call.EndedAt == nil && (call.Status == nil || *call.Status != "ended") {
+		return
+	}
+	g.record(call)
+}
+
+// Spent returns the recorded spend of key in the current day and month.
+// An empty scope returns the spend of all calls.
+func (g *BudgetGuard) Spent(scope BudgetScope, key string) (daily, monthly float64) {
+	g.mu.Lock()
+	g.init()
+	defer g.mu.Unlock()
+	day, month := g.periods(g.now())
+	k := budgetKey{scope, key}
+	if scope == "" {
+		k = budgetKey{}
+	}
+	return g.spend[budgetBucket{k, day}], g.spend[budgetBucket{k, month}]
+}
+
+func (g *BudgetGuard) record(call *Call) {
+	if call.Cost == nil && len(call.Costs) == 0 {
+		return
+	}
+	cost := callTotalCost(call)
+
+	g.mu.Lock()
+	g.init()
+	defer g.mu.Unlock()
+	at := g.now()
+	if call.EndedAt != nil {
+		at = *call.EndedAt
+	}
+	keys := g.budgetKeys(call)
+	if call.ID != nil {
+		if g.recorded[*call.ID] {
+			return
+		}
+		g.recorded[*call.ID] = true
+		// The keys the call was reserved under may include a campaign the
+		// polled call no longer carries
+		if r := g.byCall[*call.ID]; r != nil {
+			keys = r.keys
+			delete(g.byCall, *call.ID)
+			delete(g.inFlight, r)
+		}
+	}
+
+	day, month := g.periods(at)
+	for _, k := range keys {
+		g.spend[budgetBucket{k, day}] += cost
+		g.spend[budgetBucket{k, month}] += cost
+	}
+	if minutes := callMinutes(call); minutes > 0 {
+		for _, a := range []string{"", callAssistantKey(call)} {
+			h := g.history[a]
+			if h == nil {
+				h = &costHistory{}
+				g.history[a] = h
+			}
+			h.cost += cost
+			h.minutes += minutes
+		}
+	}
+	g.notify()
+}
+
+// reserve counts the worst-case cost of call against the limits before it
+// is placed. In BudgetQueue mode it waits for room in the budget.
+func (g *BudgetGuard) reserve(ctx context.Context, call *Call) (*budgetReservation, error) {
+	if g == nil {
+		return nil, nil
+	}
+	r := &budgetReservation{keys: g.budgetKeys(call)}
+	for {
+		g.mu.Lock()
+		g.init()
+		g.expire()
+		seconds := maxDurationSeconds(call)
+		r.estimate = g.estimate(call, seconds)
+		ttl := g.ReservationTTL
+		if ttl <= 0 {
+			ttl = time.Duration(seconds)*time.Second + defaultReservationGrace
+		}
+		r.expires = g.now().Add(ttl)
+		err := g.check(r)
+		if err == nil {
+			g.inFlight[r] = true
+			g.mu.Unlock()
+			return r, nil
+		}
+		changed := g.changed
+		g.mu.Unlock()
+		// A call estimated above a whole cap would wait forever
+		var exceeded *BudgetExceededError
+		if g.Mode != BudgetQueue || errors.As(err, &exceeded) && exceeded.Estimate > exceeded.Cap {
+			return nil, err
+		}
+
+		// Wait for a call to finish, or for a new day or month to start
+		timer := time.NewTimer(time.Minute)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, fmt.Errorf("%w while queued: %v", ctx.Err(), err)
+		case <-changed:
+			timer.Stop()
+		case <-timer.C:
+		}
+	}
+}
+
+// started ties a reservation to the call ID Vapi assigned, so the call's
+// cost can replace the estimate once it is recorded
+func (g *BudgetGuard) started(r *budgetReservation, call *Call) {
+	if g == nil || r == nil {
+		return
+	}
+	g.mu.Lock()
+	g.init()
+	defer g.mu.Unlock()
+	if call.ID == nil || g.recorded[*call.ID] {
+		delete(g.inFlight, r)
+		g.notify()
+		return
+	}
+	r.callID = *call.ID
+	g.byCall[r.callID] = r
+}
+
+// expire drops reservations for calls whose cost was never recorded, so a
+// missed report does not hold budget forever. The caller holds g.mu.
+func (g *BudgetGuard) expire() {
+	now := g.now()
+	expired := false
+	for r := range g.inFlight {
+		if now.After(r.expires) {
+			delete(g.inFlight, r)
+			if r.callID != "" {
+				delete(g.byCall, r.callID)
+			}
+			expired = true
+		}
+	}
+	if expired {
+		g.notify()
+	}
+}
+
+// cancel releases a reservation for a call that was never placed
+func (g *BudgetGuard) cancel(r *budgetReservation) {
+	if g == nil || r == nil {
+		return
+	}
+	g.mu.Lock()
+	g.init()
+	defer g.mu.Unlock()
+	delete(g.inFlight, r)
+	g.notify()
+}
+
+// check returns an error for the first cap r would exceed
+func (g *BudgetGuard) check(r *budgetReservation) error {
+	day, month := g.periods(g.now())
+	for _, limit := range g.Limits {
+		for _, k := range r.keys {
+			if !limit.applies(k) {
+				continue
+			}
+			for _, p := range []struct {
+				name, period string
+				cap          float64
+			}{{"daily", day, limit.Daily}, {"monthly", month, limit.Monthly}} {
+				if p.cap <= 0 {
+					continue
+				}
+				spent := g.spend[budgetBucket{k, p.period}]
+				inFlight := g.inFlightFor(k)
+				if spent+inFlight+r.estimate > p.cap {
+					return &BudgetExceededError{Limit: limit, Key: k.value, Period: p.name, Cap: p.cap,
+						Spent: spent, InFlight: inFlight, Estimate: r.estimate}
+				}
+			}
+		}
+	}
+	return nil
+}
+
+func (l BudgetLimit) applies(k budgetKey) bool {
+	switch l.Key {
+	case "":
+		return k == budgetKey{}
+	case BudgetEach:
+		return k.scope == l.Scope
+	}
+	return k.scope == l.Scope && k.value == l.Key
+}
+
+func (g *BudgetGuard) inFlightFor(k budgetKey) float64 {
+	var total float64
+	for r := range g.inFlight {
+		for _, rk := range r.keys {
+			if rk == k {
+				total += r.estimate
+				break
+			}
+		}
+	}
+	return total
+}
+
+// maxDurationSeconds is the longest the call can run
+func maxDurationSeconds(call *Call) int {
+	for _, a := range []*Assistant{call.AssistantOverrides, call.Assistant} {
+		if a != nil && a.MaxDurationSeconds != nil {
+			return *a.MaxDurationSeconds
+		}
+	}
+	return defaultMaxDurationSeconds
+}
+
+// estimate is the most a call running for seconds can cost, at the cost per
+// minute of earlier calls with the same assistant, or of all earlier calls
+func (g *BudgetGuard) estimate(call *Call, seconds int) float64 {
+	rate := g.CostPerMinute
+	if rate <= 0 {
+		rate = DefaultCostPerMinute
+	}
+	for _, a := range []string{callAssistantKey(call), ""} {
+		if h := g.history[a]; h != nil && h.minutes >= 1 {
+			rate = h.cost / h.minutes
+			break
+		}
+	}
+	return float64(seconds) / 60 * rate
+}
+
+func (g *BudgetGuard) periods(t time.Time) (day, month string) {
+	loc := g.Location
+	if loc == nil {
+		loc = time.UTC
+	}
+	t = t.In(loc)
+	return t.Format("2006-01-02"), t.Format("2006-01")
+}
+
+// notify wakes calls queued for budget. The caller holds g.mu.
+func (g *BudgetGuard) notify() {
+	close(g.changed)
+	g.changed = make(chan struct{})
+}
+
+// budgetKeys returns every key the spend of call counts toward. The org is
+// the guard's, falling back to the org of a polled call.
+func (g *BudgetGuard) budgetKeys(call *Call) []budgetKey {
+	keys := []budgetKey{{}}
+	org := g.OrgID
+	if org == "" && call.OrgID != nil {
+		org = *call.OrgID
+	}
+	if org != "" {
+		keys = append(keys, budgetKey{BudgetOrg, org})
+	}
+	if a := callAssistantKey(call); a != "" {
+		keys = append(keys, budgetKey{BudgetAssistant, a})
+	}
+	for _, a := range []*Assistant{call.AssistantOverrides, call.Assistant} {
+		if a == nil {
+			continue
+		}
+		if campaign, ok := a.Metadata[MetadataCampaign].(string); ok && campaign != "" {
+			keys = append(keys, budgetKey{BudgetCampaign, campaign})
+			break
+		}
+	}
+	return keys
+}
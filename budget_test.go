@@ -0,0 +1,158 @@
+package vapi
+
+import (
+	"context"
+	"errors"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestBudgetGuard(t *testing.T) {
+	var created int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		created++
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte(`{"id": "call-` + strconv.Itoa(created) + `"}`))
+	}))
+	defer srv.Close()
+	oldBase := apiBaseURL
+	apiBaseURL = srv.URL
+	defer func() { apiBaseURL = oldBase }()
+	t.Setenv("VAPI_API_KEY", "test")
+
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	guard := NewBudgetGuard(BudgetReject,
+		BudgetLimit{Daily: 10},
+		BudgetLimit{Scope: BudgetCampaign, Key: BudgetEach, Daily: 2},
+	)
+	guard.now = func() time.Time { return now }
+	SetBudgetGuard(guard)
+	defer SetBudgetGuard(nil)
+
+	// Ten minutes at the default rate of $0.15/min is $1.50
+	maxDuration := 600
+	campaign := func(name string) Call {
+		return Call{
+			PhoneNumberID: stringPtr("pn-1"),
+			Customer:      &Customer{Number: "+15551234567"},
+			Assistant: &Assistant{
+				Name:               stringPtr("outbound"),
+				MaxDurationSeconds: &maxDuration,
+				Metadata:           map[string]any{MetadataCampaign: name},
+			},
+		}
+	}
+
+	first, err := CreateCall(context.Background(), campaign("spring"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = CreateCall(context.Background(), campaign("spring"))
+	var exceeded *BudgetExceededError
+	if !errors.As(err, &exceeded) || exceeded.Key != "spring" || exceeded.InFlight != 1.5 {
+		t.Fatalf("second spring call: err = %v", err)
+	}
+	if _, err := CreateCall(context.Background(), campaign("summer")); err != nil {
+		t.Fatalf("summer call: %v", err)
+	}
+
+	// The first call ends after four minutes costing $0.40, which frees the
+	// rest of its estimate and sets the cost per minute to $0.10
+	start, end := now.Add(-4*time.Minute), now
+	cost := 0.40
+	guard.RecordReport(&EndOfCallReport{Call: first, StartedAt: &start, EndedAt: &end, Cost: &cost})
+	guard.RecordCall(&Call{ID: first.ID, EndedAt: &end, Cost: &cost})
+	if daily, monthly := guard.Spent(BudgetCampaign, "spring"); daily != 0.40 || monthly != 0.40 {
+		t.Errorf("spring spend = %v / %v, want 0.40 recorded once", daily, monthly)
+	}
+	if _, err := CreateCall(context.Background(), campaign("spring")); err != nil {
+		t.Fatalf("spring call after report: %v", err)
+	}
+	if r := guard.byCall["call-3"]; r == nil || math.Abs(r.estimate-1.0) > 1e-9 {
+		t.Errorf("estimate from history = %+v, want $1.00", r)
+	}
+}
+
+func TestBudgetGuard_Queue(t *testing.T) {
+	guard := NewBudgetGuard(BudgetQueue, BudgetLimit{Daily: 2})
+	call := &Call{Assistant: &Assistant{Name: stringPtr("a")}}
+	first, err := guard.reserve(context.Background(), call)
+	if err != nil {
+		t.Fatal(err)
+	}
+	guard.started(first, &Call{ID: stringPtr("call-1")})
+
+	done := make(chan error)
+	go func() {
+		_, err := guard.reserve(context.Background(), call)
+		done <- err
+	}()
+	select {
+	case err := <-done:
+		t.Fatalf("queued call placed while over budget: %v", err)
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	cost := 0.20
+	guard.RecordCall(&Call{ID: stringPtr("call-1"), Status: stringPtr("ended"), Cost: &cost})
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("queued call not released when the running call ended")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := guard.reserve(ctx, call); !errors.Is(err, context.Canceled) {
+		t.Errorf("reserve with canceled context: err = %v", err)
+	}
+	// A call that could never fit is rejected rather than queued forever
+	tiny := &BudgetGuard{Mode: BudgetQueue, Limits: []BudgetLimit{{Daily: 0.5}}}
+	var exceeded *BudgetExceededError
+	if _, err := tiny.reserve(context.Background(), call); !errors.As(err, &exceeded) || exceeded.Estimate <= exceeded.Cap {
+		t.Errorf("reserve above the cap: err = %v", err)
+	}
+	cost = 0.1
+	tiny.RecordCall(&Call{ID: stringPtr("call-2"), Status: stringPtr("ended"), Cost: &cost})
+	if daily, _ := tiny.Spent("", ""); daily != 0.1 {
+		t.Errorf("struct literal guard spend = %v, want 0.1", daily)
+	}
+}
+
+func TestBudgetGuard_OrgAndExpiry(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	guard := NewBudgetGuard(BudgetReject, BudgetLimit{Scope: BudgetOrg, Key: "org-1", Daily: 2})
+	guard.OrgID = "org-1"
+	guard.ReservationTTL = time.Hour
+	guard.now = func() time.Time { return now }
+
+	// Call requests carry no org ID, so the limit applies through the guard's
+	call := &Call{Assistant: &Assistant{Name: stringPtr("a")}}
+	r, err := guard.reserve(context.Background(), call)
+	if err != nil {
+		t.Fatal(err)
+	}
+	guard.started(r, &Call{ID: stringPtr("call-1")})
+	var exceeded *BudgetExceededError
+	if _, err := guard.reserve(context.Background(), call); !errors.As(err, &exceeded) || exceeded.Key != "org-1" {
+		t.Fatalf("second org call: err = %v", err)
+	}
+
+	// The first call's report never arrives; its reservation lapses
+	now = now.Add(time.Hour + time.Second)
+	if _, err := guard.reserve(context.Background(), call); err != nil {
+		t.Fatalf("call after the reservation expired: %v", err)
+	}
+	if guard.byCall["call-1"] != nil {
+		t.Error("expired reservation still tied to its call")
+	}
+}
+
+func stringPtr(s string) *string { return &s }
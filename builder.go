@@ -0,0 +1,247 @@
+package vapi
+
+import (
+	"encoding/json"
+)
+
+// cloneAssistant returns a deep copy of a
+func cloneAssistant(a *Assistant) (*Assistant, error) {
+	b, err := json.Marshal(a)
+	if err != nil {
+		return nil, err
+	}
+	var c Assistant
+	if err := json.Unmarshal(b, &c); err != nil {
+		return nil, err
+	}
+	return &c, nil
+}
+
+// AssistantBuilder constructs an Assistant step by step:
+//
+//	a, err := vapi.NewAssistant("Alex").
+//		WithPrompt(prompt).
+//		WithFirstMessage("Hi, this is Alex.").
+//		WithTools(lookupOrder).
+//		Build()
+//
+// The builder starts from copies of the package defaults, and every call to
+// Build returns an independent deep copy, so changing one assistant never
+// affects another or the defaults.
+type AssistantBuilder struct {
+	assistant Assistant
+	locale    string
+	err       error
+}
+
+// NewAssistant starts an assistant with the given name and the default
+// model, voice and transcriber.
+func NewAssistant(name string) *AssistantBuilder {
+	model := DefaultModelConfig
+	model.Messages = append([]ModelMessage(nil), model.Messages...)
+	model.Tools = append([]Tool(nil), model.Tools...)
+	model.ToolIDs = append([]string(nil), model.ToolIDs...)
+	voice := DefaultElevenLabsVoiceConfig
+	transcriber := DefaultTranscriber
+
+	return &AssistantBuilder{
+		assistant: Assistant{
+			Name:                   &name,
+			Voice:                  &voice,
+			Model:                  &model,
+			Transcriber:            &transcriber,
+			ServerMessages:         []string{"end-of-call-report", "function-call", "tool-calls"},
+			EndCallFunctionEnabled: true,
+			EndCallPhrases:         []string{"goodbye"},
+		},
+	}
+}
+
+// WithPrompt sets the system prompt, replacing any existing system message
+func (b *AssistantBuilder) WithPrompt(prompt string) *AssistantBuilder {
+	messages := []ModelMessage{{Role: "system", Content: prompt}}
+	for _, m := range b.assistant.Model.Messages {
+		if m.Role != "system" {
+			messages = append(messages, m)
+		}
+	}
+	b.assistant.Model.Messages = messages
+	return b
+}
+
+// WithFirstMessage sets the first thing the assistant says
+func (b *AssistantBuilder) WithFirstMessage(msg string) *AssistantBuilder {
+	b.assistant.FirstMessage = &msg
+	return b
+}
+
+// WithEndCallMessage sets what the assistant says before hanging up
+func (b *AssistantBuilder) WithEndCallMessage(msg string) *AssistantBuilder {
+	b.assistant.EndCallMessage = &msg
+	return b
+}
+
+// WithVoicemailMessage sets the message left when a voicemail is detected
+func (b *AssistantBuilder) WithVoicemailMessage(msg string) *AssistantBuilder {
+	b.assistant.VoicemailMessage = &msg
+	return b
+}
+
+// WithVoice replaces the voice configuration
+func (b *AssistantBuilder) WithVoice(voice ElevenLabsVoiceConfig) *AssistantBuilder {
+	b.assistant.Voice = &voice
+	return b
+}
+
+// WithModel replaces the model configuration. Messages and tools already on
+// the builder are kept when model does not set its own.
+func (b *AssistantBuilder) WithModel(model ModelConfig) *AssistantBuilder {
+	if len(model.Messages) == 0 {
+		model.Messages = b.assistant.Model.Messages
+	}
+	if len(model.Tools) == 0 {
+		model.Tools = b.assistant.Model.Tools
+	}
+	b.assistant.Model = &model
+	return b
+}
+
+// WithTranscriber replaces the transcriber configuration
+func (b *AssistantBuilder) WithTranscriber(transcriber TranscriberConfig) *AssistantBuilder {
+	b.assistant.Transcriber = &transcriber
+	return b
+}
+
+// WithTools adds inline tools to the model
+func (b *AssistantBuilder) WithTools(tools ...Tool) *AssistantBuilder {
+	b.assistant.Model.Tools = append(b.assistant.Model.Tools, tools...)
+	return b
+}
+
+// WithToolIDs adds references to tools created through the API
+func (b *AssistantBuilder) WithToolIDs(ids ...string) *AssistantBuilder {
+	b.assistant.Model.ToolIDs = append(b.assistant.Model.ToolIDs, ids...)
+	return b
+}
+
+// WithServer sets the webhook server the assistant reports to
+func (b *AssistantBuilder) WithServer(url string) *AssistantBuilder {
+	b.assistant.Server = &ServerConfig{URL: url}
+	return b
+}
+
+// WithAnalysisPlan sets the post-call analysis plan
+func (b *AssistantBuilder) WithAnalysisPlan(plan AnalysisPlan) *AssistantBuilder {
+	b.assistant.AnalysisPlan = &plan
+	return b
+}
+
+// WithMaxDuration limits the length of calls using the assistant
+func (b *AssistantBuilder) WithMaxDuration(seconds int) *AssistantBuilder {
+	b.assistant.MaxDurationSeconds = &seconds
+	return b
+}
+
+// WithVariableValues adds values for {{variable}} placeholders
+func (b *AssistantBuilder) WithVariableValues(vars map[string]any) *AssistantBuilder {
+	if b.assistant.VariableValues == nil {
+		b.assistant.VariableValues = map[string]any{}
+	}
+	for k, v := range vars {
+		b.assistant.VariableValues[k] = v
+	}
+	return b
+}
+
+// Build validates the assistant and returns a deep copy of it
+func (b *AssistantBuilder) Build() (*Assistant, error) {
+	if b.err != nil {
+		return nil, b.err
+	}
+	a, err := cloneAssistant(&b.assistant)
+	if err != nil {
+		return nil, err
+	}
+	if err := a.Validate(); err != nil {
+		return nil, err
+	}
+	return a, nil
+}
+
+// CallBuilder constructs a Call request:
+//
+//	call, err := vapi.NewOutboundCall(phoneNumberID, vapi.Customer{Number: "+14155550123"}).
+//		WithAssistantID(assistantID).
+//		Build()
+type CallBuilder struct {
+	call Call
+}
+
+// NewOutboundCall starts an outbound phone call from the given phone number
+// to the customer.
+func NewOutboundCall(phoneNumberID string, customer Customer) *CallBuilder {
+	return &CallBuilder{
+		call: Call{
+			PhoneNumberID: &phoneNumberID,
+			Customer:      &customer,
+		},
+	}
+}
+
+// WithAssistant uses an inline assistant for the call
+func (b *CallBuilder) WithAssistant(a *Assistant) *CallBuilder {
+	b.call.Assistant = a
+	return b
+}
+
+// WithAssistantID uses a saved assistant for the call
+func (b *CallBuilder) WithAssistantID(id string) *CallBuilder {
+	b.call.AssistantID = &id
+	return b
+}
+
+// WithAssistantOverrides overrides parts of the assistant for this call only
+func (b *CallBuilder) WithAssistantOverrides(overrides *Assistant) *CallBuilder {
+	b.call.AssistantOverrides = overrides
+	return b
+}
+
+// WithSquad uses an inline squad for the call
+func (b *CallBuilder) WithSquad(squad *Squad) *CallBuilder {
+	b.call.Squad = squad
+	return b
+}
+
+// WithSquadID uses a saved squad for the call
+func (b *CallBuilder) WithSquadID(id string) *CallBuilder {
+	b.call.SquadID = &id
+	return b
+}
+
+// WithName names the call
+func (b *CallBuilder) WithName(name string) *CallBuilder {
+	b.call.Name = &name
+	return b
+}
+
+// WithArtifactPlan sets what artifacts (recordings, transcripts) to keep
+func (b *CallBuilder) WithArtifactPlan(plan ArtifactPlan) *CallBuilder {
+	b.call.ArtifactPlan = &plan
+	return b
+}
+
+// Build validates the call and returns a deep copy of it
+func (b *CallBuilder) Build() (*Call, error) {
+	data, err := json.Marshal(&b.call)
+	if err != nil {
+		return nil, err
+	}
+	var c Call
+	if err := json.Unmarshal(data, &c); err != nil {
+		return nil, err
+	}
+	if err := c.Validate(); err != nil {
+		return nil, err
+	}
+	return &c, nil
+}
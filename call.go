@@ -13,19 +13,40 @@ const (
 	VoiceMailDetectionProviderTwilio = "twilio"
 )
 
-// CreateCall creates a new call with the given configuration
+// CreateCall creates a new call with the given configuration. The call is
+// validated first and every violation is returned as ValidationErrors. When
+// a budget guard is set the call must also fit within its limits.
 func CreateCall(ctx context.Context, call Call) (*Call, error) {
 	if ctx.Err() != nil {
 		return nil, ctx.Err()
 	}
 
+	if err := call.Validate(); err != nil {
+		return nil, err
+	}
+
+	guard := currentBudgetGuard()
+	reservation, err := guard.reserve(ctx, &call)
+	if err != nil {
+		return nil, err
+	}
+	result, err := createCall(call)
+	if err != nil {
+		guard.cancel(reservation)
+		return nil, err
+	}
+	guard.started(reservation, result)
+	return result, nil
+}
+
+func createCall(call Call) (*Call, error) {
 	b, err := json.Marshal(call)
 	if err != nil {
 		return nil, err
 	}
 
 	buf := bytes.NewBuffer(b)
-	req, err := http.NewRequest("POST", "https://api.vapi.ai/call", buf)
+	req, err := http.NewRequest("POST", apiBaseURL+"/call", buf)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create request for call: %w", err)
 	}
@@ -55,20 +76,21 @@ func CreateCall(ctx context.Context, call Call) (*Call, error) {
 	}
 
 	var result Call
-	if err := json.Unmarshal(body.Bytes(), &result); err != nil {
+	if err := decodeResponse(body.Bytes(), &result); err != nil {
 		return nil, fmt.Errorf("failed to unmarshal response: %w", err)
 	}
 
 	return &result, nil
 }
 
-// GetCall retrieves a call by its ID
+// GetCall retrieves a call by its ID. Ended calls are recorded with the
+// budget guard, if one is set.
 func GetCall(ctx context.Context, id string) (*Call, error) {
 	if ctx.Err() != nil {
 		return nil, ctx.Err()
 	}
 
-	req, err := http.NewRequest("GET", fmt.Sprintf("https://api.vapi.ai/call/%s", id), nil)
+	req, err := http.NewRequest("GET", fmt.Sprintf("%s/call/%s", apiBaseURL, id), nil)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create request for call: %w", err)
 	}
@@ -97,10 +119,11 @@ func GetCall(ctx context.Context, id string) (*Call, error) {
 	}
 
 	var result Call
-	if err := json.Unmarshal(body.Bytes(), &result); err != nil {
+	if err := decodeResponse(body.Bytes(), &result); err != nil {
 		return nil, fmt.Errorf("failed to unmarshal response: %w", err)
 	}
 
+	currentBudgetGuard().RecordCall(&result)
 	return &result, nil
 }
 
@@ -3,6 +3,7 @@ package vapi
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"os"
 	"testing"
 )
@@ -118,3 +119,57 @@ func TestEndOfCallReport_Unmarshal(t *testing.T) {
 		t.Logf("Call Cost: $%.2f", *report.EndOfCallReport.Cost)
 	}
 }
+
+func TestCall_Validate(t *testing.T) {
+	assistantID := "asst-1"
+	model := "eleven_v9"
+	call := Call{
+		AssistantID: &assistantID,
+		Assistant: &Assistant{
+			Voice: &ElevenLabsVoiceConfig{Provider: "11labs", VoiceID: "v", Model: model, Stability: 1.5},
+			Model: &ModelConfig{Provider: "openai", Model: "gpt-4o", Temperature: 3},
+		},
+		Customer: &Customer{Number: "555-1234"},
+	}
+
+	err := call.Validate()
+	var errs ValidationErrors
+	if !errors.As(err, &errs) {
+		t.Fatalf("Validate() error = %v, want ValidationErrors", err)
+	}
+
+	want := []string{
+		"assistantId",
+		"assistant.voice.model",
+		"assistant.voice.stability",
+		"assistant.model.temperature",
+		"customer.number",
+	}
+	if len(errs) != len(want) {
+		t.Fatalf("got %d errors, want %d: %v", len(errs), len(want), errs)
+	}
+	for i, field := range want {
+		if errs[i].Field != field {
+			t.Errorf("errs[%d].Field = %q, want %q", i, errs[i].Field, field)
+		}
+	}
+
+	if _, err := CreateCall(context.Background(), call); !errors.As(err, &errs) {
+		t.Errorf("CreateCall() error = %v, want ValidationErrors", err)
+	}
+
+	valid := Call{AssistantID: &assistantID, Customer: &Customer{Number: "+14155550123"}}
+	if err := valid.Validate(); err != nil {
+		t.Errorf("Validate() on valid call error = %v", err)
+	}
+
+	// The phone number's assistant answers when none is given
+	phoneNumberID := "pn-1"
+	inbound := Call{PhoneNumberID: &phoneNumberID, Customer: &Customer{Number: "+14155550123"}}
+	if err := inbound.Validate(); err != nil {
+		t.Errorf("Validate() with only a phone number error = %v", err)
+	}
+	if err := (&Call{Customer: &Customer{Number: "+14155550123"}}).Validate(); err == nil {
+		t.Error("Validate() without assistant or phone number error = nil")
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"time"
 
+	"github.com/chriscow/minds"
 	"github.com/sashabaranov/go-openai"
 )
 
@@ -11,57 +12,89 @@ import (
 type ServerConfig struct {
 	URL            string `json:"url"`
 	TimeoutSeconds int    `json:"timeoutSeconds"`
+
+	Extra map[string]json.RawMessage `json:"-"`
 }
 
 // Voice represents voice configuration
 type Voice struct {
 	Provider string `json:"provider"`
 	VoiceID  string `json:"voiceId"`
+
+	Extra map[string]json.RawMessage `json:"-"`
 }
 
 // Model represents model configuration
 type Model struct {
 	Provider string `json:"provider"`
 	Model    string `json:"model"`
+
+	Extra map[string]json.RawMessage `json:"-"`
 }
 
 // Transcriber represents transcriber configuration
 type Transcriber struct {
 	Provider string `json:"provider"`
+
+	Extra map[string]json.RawMessage `json:"-"`
 }
 
 // VoicemailDetection represents voicemail detection configuration
 type VoicemailDetection struct {
 	Provider                         string  `json:"provider"`
 	VoicemailExpectedDurationSeconds float64 `json:"voicemailExpectedDurationSeconds"`
+
+	Extra map[string]json.RawMessage `json:"-"`
 }
 
 // KnowledgeBase represents knowledge base configuration
 type KnowledgeBase struct {
 	Server ServerConfig `json:"server"`
+
+	Extra map[string]json.RawMessage `json:"-"`
 }
 
 // Tool represents tool configuration
 type Tool struct {
-	Type  string `json:"type"`
-	Async bool   `json:"async"`
+	ID       *string       `json:"id,omitempty"`
+	Type     string        `json:"type"`
+	Async    bool          `json:"async"`
+	Function *ToolFunction `json:"function,omitempty"`
+	Server   *ServerConfig `json:"server,omitempty"`
+
+	Extra map[string]json.RawMessage `json:"-"`
+}
+
+// ToolFunction describes the function the model calls for a function tool
+type ToolFunction struct {
+	Name        string            `json:"name"`
+	Description string            `json:"description,omitempty"`
+	Parameters  *minds.Definition `json:"parameters,omitempty"`
+
+	Extra map[string]json.RawMessage `json:"-"`
 }
 
 // ChunkPlan represents chunk configuration
 type ChunkPlan struct {
 	Enabled       bool `json:"enabled"`
 	MinCharacters int  `json:"minCharacters"`
+
+	Extra map[string]json.RawMessage `json:"-"`
 }
 
 // FallbackPlan represents fallback configuration
 type FallbackPlan struct {
 	Voices []FallbackVoice `json:"voices"`
+
+	Extra map[string]json.RawMessage `json:"-"`
 }
 
 // FallbackVoice represents fallback voice configuration
 type FallbackVoice struct {
 	Provider string `json:"provider"`
 	VoiceID  string `json:"voiceId"`
+
+	Extra map[string]json.RawMessage `json:"-"`
 }
 
 // TransportConfig represents transport configuration
@@ -69,6 +102,8 @@ type TransportConfig struct {
 	Provider string `json:"provider"`
 	Timeout  int    `json:"timeout"`
 	Record   bool   `json:"record"`
+
+	Extra map[string]json.RawMessage `json:"-"`
 }
 
 // MessagePlan represents message configuration
@@ -76,6 +111,8 @@ type MessagePlan struct {
 	IdleMessages              []string `json:"idleMessages"`
 	IdleMessageMaxSpokenCount float64  `json:"idleMessageMaxSpokenCount"`
 	IdleTimeoutSeconds        float64  `json:"idleTimeoutSeconds"`
+
+	Extra map[string]json.RawMessage `json:"-"`
 }
 
 // CustomEndpointingRule represents custom endpointing configuration
@@ -84,12 +121,16 @@ type CustomEndpointingRule struct {
 	AssistantRegex string  `json:"assistantRegex"`
 	CustomerRegex  string  `json:"customerRegex"`
 	TimeoutSeconds float64 `json:"timeoutSeconds"`
+
+	Extra map[string]json.RawMessage `json:"-"`
 }
 
 // MonitorPlan represents monitoring configuration
 type MonitorPlan struct {
 	ListenEnabled  bool `json:"listenEnabled"`
 	ControlEnabled bool `json:"controlEnabled"`
+
+	Extra map[string]json.RawMessage `json:"-"`
 }
 
 // Cost represents a cost entry in the call
@@ -98,6 +139,8 @@ type Cost struct {
 	Cost     float64 `json:"cost"`
 	Minutes  float64 `json:"minutes"`
 	Provider string  `json:"provider"`
+
+	Extra map[string]json.RawMessage `json:"-"`
 }
 
 // CostBreakdown represents the detailed cost breakdown of a call
@@ -113,17 +156,9 @@ type CostBreakdown struct {
 	LlmCompletionTokens int `json:"llmCompletionTokens"`
 	TtsCharacters       int `json:"ttsCharacters"`
 
-	AnalysisCostBreakdown struct {
-		Summary                           float64 `json:"summary"`
-		SummaryPromptTokens               int     `json:"summaryPromptTokens"`
-		SummaryCompletionTokens           int     `json:"summaryCompletionTokens"`
-		StructuredData                    float64 `json:"structuredData"`
-		StructuredDataPromptTokens        int     `json:"structuredDataPromptTokens"`
-		StructuredDataCompletionTokens    int     `json:"structuredDataCompletionTokens"`
-		SuccessEvaluation                 float64 `json:"successEvaluation"`
-		SuccessEvaluationPromptTokens     int     `json:"successEvaluationPromptTokens"`
-		SuccessEvaluationCompletionTokens int     `json:"successEvaluationCompletionTokens"`
-	} `json:"analysisCostBreakdown"`
+	AnalysisCostBreakdown AnalysisCostBreakdown `json:"analysisCostBreakdown"`
+
+	Extra map[string]json.RawMessage `json:"-"`
 }
 
 // Analysis represents the analysis results of a call
@@ -134,25 +169,31 @@ type Analysis struct {
 	// I had a *string here as that is what the documentation says, but I started getting:
 	// "cannot unmarshal bool into Go struct field Analysis.analysis.successEvaluation of type string"
 	SuccessEvaluation any `json:"successEvaluation,omitempty"`
+
+	Extra map[string]json.RawMessage `json:"-"`
 }
 
 // AnalysisCostBreakdown represents the cost breakdown for analysis
 type AnalysisCostBreakdown struct {
 	Summary                           float64 `json:"summary"`
-	SummaryPromptTokens               float64 `json:"summaryPromptTokens"`
-	SummaryCompletionTokens           float64 `json:"summaryCompletionTokens"`
+	SummaryPromptTokens               int     `json:"summaryPromptTokens"`
+	SummaryCompletionTokens           int     `json:"summaryCompletionTokens"`
 	StructuredData                    float64 `json:"structuredData"`
-	StructuredDataPromptTokens        float64 `json:"structuredDataPromptTokens"`
-	StructuredDataCompletionTokens    float64 `json:"structuredDataCompletionTokens"`
+	StructuredDataPromptTokens        int     `json:"structuredDataPromptTokens"`
+	StructuredDataCompletionTokens    int     `json:"structuredDataCompletionTokens"`
 	SuccessEvaluation                 float64 `json:"successEvaluation"`
-	SuccessEvaluationPromptTokens     float64 `json:"successEvaluationPromptTokens"`
-	SuccessEvaluationCompletionTokens float64 `json:"successEvaluationCompletionTokens"`
+	SuccessEvaluationPromptTokens     int     `json:"successEvaluationPromptTokens"`
+	SuccessEvaluationCompletionTokens int     `json:"successEvaluationCompletionTokens"`
+
+	Extra map[string]json.RawMessage `json:"-"`
 }
 
 // Monitor represents monitoring URLs for a call
 type Monitor struct {
 	ListenUrl  string `json:"listenUrl"`
 	ControlUrl string `json:"controlUrl"`
+
+	Extra map[string]json.RawMessage `json:"-"`
 }
 
 // Artifact represents call artifacts like recordings and transcripts
@@ -165,12 +206,16 @@ type Artifact struct {
 	VideoRecordingStartDelaySeconds int                            `json:"videoRecordingStartDelaySeconds"`
 	Transcript                      string                         `json:"transcript"`
 	PcapUrl                         string                         `json:"pcapUrl"`
+
+	Extra map[string]json.RawMessage `json:"-"`
 }
 
 // Transport represents transport configuration for a call
 type Transport struct {
 	Provider              string `json:"provider"`
 	AssistantVideoEnabled bool   `json:"assistantVideoEnabled"`
+
+	Extra map[string]json.RawMessage `json:"-"`
 }
 
 // PhoneNumber represents phone number configuration
@@ -183,6 +228,8 @@ type PhoneNumber struct {
 	AssistantID         *string       `json:"assistantId,omitempty"`
 	SquadID             *string       `json:"squadId,omitempty"`
 	Server              *ServerConfig `json:"server,omitempty"`
+
+	Extra map[string]json.RawMessage `json:"-"`
 }
 
 // Destination represents call destination configuration
@@ -195,6 +242,8 @@ type Destination struct {
 	Message                string       `json:"message"`
 	NumberE164CheckEnabled bool         `json:"numberE164CheckEnabled"`
 	TransferPlan           TransferPlan `json:"transferPlan"`
+
+	Extra map[string]json.RawMessage `json:"-"`
 }
 
 // TransferPlan represents call transfer configuration
@@ -203,13 +252,18 @@ type TransferPlan struct {
 	Message string  `json:"message"`
 	SipVerb *string `json:"sipVerb"`
 	Twiml   string  `json:"twiml"`
+
+	Extra map[string]json.RawMessage `json:"-"`
 }
 
 // Squad represents a squad configuration
 type Squad struct {
+	ID               *string   `json:"id,omitempty"`
 	Members          []any     `json:"members"`
 	Name             string    `json:"name"`
 	MembersOverrides Assistant `json:"membersOverrides"`
+
+	Extra map[string]json.RawMessage `json:"-"`
 }
 
 // ArtifactPlan represents the configuration for call artifacts
@@ -221,6 +275,8 @@ type ArtifactPlan struct {
 	PcapS3PathPrefix      string         `json:"pcapS3PathPrefix"`
 	TranscriptPlan        TranscriptPlan `json:"transcriptPlan"`
 	RecordingPath         string         `json:"recordingPath"`
+
+	Extra map[string]json.RawMessage `json:"-"`
 }
 
 // TranscriptPlan represents the configuration for call transcripts
@@ -228,6 +284,8 @@ type TranscriptPlan struct {
 	Enabled       bool   `json:"enabled"`
 	AssistantName string `json:"assistantName,omitempty"`
 	UserName      string `json:"userName,omitempty"`
+
+	Extra map[string]json.RawMessage `json:"-"`
 }
 
 // Message represents a message in the call
@@ -237,12 +295,46 @@ type Message struct {
 	Time             float64 `json:"time"`
 	EndTime          float64 `json:"endTime"`
 	SecondsFromStart float64 `json:"secondsFromStart"`
-	Duration         float64 `json:"duration,omitempty"`
+	Duration         float64 `json:"duration,omitempty"` // milliseconds
+
+	// Set on "tool_calls" messages
+	ToolCalls []ToolCall `json:"toolCalls,omitempty"`
+
+	// Set on "tool_call_result" messages
+	Name       string `json:"name,omitempty"`
+	Result     string `json:"result,omitempty"`
+	ToolCallID string `json:"toolCallId,omitempty"`
+
+	Extra map[string]json.RawMessage `json:"-"`
+}
+
+// ToolCall is a tool invocation requested by the model
+type ToolCall struct {
+	ID       string           `json:"id"`
+	Type     string           `json:"type"`
+	Function ToolCallFunction `json:"function"`
+
+	Extra map[string]json.RawMessage `json:"-"`
+}
+
+// ToolCallFunction is the function a ToolCall invokes. Arguments is the
+// JSON-encoded argument object.
+type ToolCallFunction struct {
+	Name      string `json:"name"`
+	Arguments string `json:"arguments"`
+
+	Extra map[string]json.RawMessage `json:"-"`
+
+	// rawArguments holds object arguments as decoded, so they are written
+	// back as an object
+	rawArguments json.RawMessage
 }
 
 type OpenAIMessage struct {
 	Role    string `json:"role"`
 	Content string `json:"content,omitempty"`
+
+	Extra map[string]json.RawMessage `json:"-"`
 }
 
 // Customer contains customer information
@@ -252,6 +344,8 @@ type Customer struct {
 	Number                 string `json:"number,omitempty"`
 	SipURI                 string `json:"sipUri,omitempty"`
 	Name                   string `json:"name,omitempty"`
+
+	Extra map[string]json.RawMessage `json:"-"`
 }
 
 // Call represents a call request
@@ -291,4 +385,6 @@ type Call struct {
 	CustomerID         *string        `json:"customerId,omitempty"`
 	Customer           *Customer      `json:"customer,omitempty"`
 	Name               *string        `json:"name,omitempty"`
+
+	Extra map[string]json.RawMessage `json:"-"`
 }
@@ -0,0 +1,147 @@
+package vapi
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"time"
+)
+
+// ConversationMetrics are quality measures of a call computed from its
+// message timestamps. Times are offsets or durations within the call.
+type ConversationMetrics struct {
+	// AssistantID, PromptName and PromptVersion identify what the call ran
+	// with, so metrics can be tracked per assistant version
+	AssistantID   string
+	PromptName    string
+	PromptVersion string
+
+	Duration time.Duration
+	Turns    int
+
+	// ResponseLatencyP50 and P95 are percentiles of the gap between the end
+	// of a user turn and the start of the assistant's reply, over Responses
+	// replies. Replies that start before the user finished are not counted.
+	ResponseLatencyP50 time.Duration
+	ResponseLatencyP95 time.Duration
+	Responses          int
+
+	AssistantTalkTime time.Duration
+	UserTalkTime      time.Duration
+	// TalkRatio is the assistant's share of the talk time, from 0 to 1
+	TalkRatio float64
+
+	// Interruptions counts user turns that start while the assistant is
+	// still speaking
+	Interruptions int
+
+	// LongestSilence is the longest stretch without anyone speaking, from
+	// the first turn to the last
+	LongestSilence time.Duration
+
+	TurnsPerMinute float64
+
+	// TimeToFirstAssistantUtterance is when the assistant first spoke. It is
+	// -1 when the assistant never spoke.
+	TimeToFirstAssistantUtterance time.Duration
+}
+
+// String summarizes the metrics on one line
+func (m ConversationMetrics) String() string {
+	return fmt.Sprintf("latency p50=%v p95=%v, talk ratio=%.2f, interruptions=%d, longest silence=%v, turns/min=%.1f, first utterance=%v",
+		m.ResponseLatencyP50, m.ResponseLatencyP95, m.TalkRatio, m.Interruptions, m.LongestSilence, m.TurnsPerMinute, m.TimeToFirstAssistantUtterance)
+}
+
+// AnalyzeCall computes the conversation metrics of a finished call
+func AnalyzeCall(c *Call) ConversationMetrics {
+	m := AnalyzeTranscript(TranscriptFromCall(c))
+
+	if c.StartedAt != nil && c.EndedAt != nil && c.EndedAt.After(*c.StartedAt) {
+		m.Duration = c.EndedAt.Sub(*c.StartedAt)
+		m.TurnsPerMinute = turnsPerMinute(m.Turns, m.Duration)
+	}
+	if c.AssistantID != nil {
+		m.AssistantID = *c.AssistantID
+	}
+	for _, a := range []*Assistant{c.AssistantOverrides, c.Assistant} {
+		if a == nil {
+			continue
+		}
+		if name, ok := a.Metadata[MetadataPromptName].(string); ok && m.PromptName == "" {
+			m.PromptName = name
+		}
+		if version, ok := a.Metadata[MetadataPromptVersion].(string); ok && m.PromptVersion == "" {
+			m.PromptVersion = version
+		}
+	}
+	return m
+}
+
+// AnalyzeTranscript computes the conversation metrics of a transcript. The
+// duration is taken to be the end of the last turn.
+func AnalyzeTranscript(t *Transcript) ConversationMetrics {
+	m := ConversationMetrics{TimeToFirstAssistantUtterance: -1}
+
+	turns := t.Turns()
+	sort.SliceStable(turns, func(i, j int) bool { return turns[i].Start < turns[j].Start })
+	m.Turns = len(turns)
+
+	var latencies []time.Duration
+	var speechEnd time.Duration
+	for i, turn := range turns {
+		talk := turn.End - turn.Start
+		switch turn.Role {
+		case RoleAssistant:
+			m.AssistantTalkTime += talk
+			if m.TimeToFirstAssistantUtterance < 0 {
+				m.TimeToFirstAssistantUtterance = turn.Start
+			}
+			if i > 0 && turns[i-1].Role == RoleUser && turn.Start >= turns[i-1].End {
+				latencies = append(latencies, turn.Start-turns[i-1].End)
+			}
+		case RoleUser:
+			m.UserTalkTime += talk
+			if i > 0 && turns[i-1].Role == RoleAssistant && turn.Start < turns[i-1].End {
+				m.Interruptions++
+			}
+		}
+
+		if i > 0 && turn.Start-speechEnd > m.LongestSilence {
+			m.LongestSilence = turn.Start - speechEnd
+		}
+		if turn.End > speechEnd {
+			speechEnd = turn.End
+		}
+	}
+	m.Duration = speechEnd
+
+	if total := m.AssistantTalkTime + m.UserTalkTime; total > 0 {
+		m.TalkRatio = float64(m.AssistantTalkTime) / float64(total)
+	}
+	m.Responses = len(latencies)
+	m.ResponseLatencyP50 = percentile(latencies, 50)
+	m.ResponseLatencyP95 = percentile(latencies, 95)
+	m.TurnsPerMinute = turnsPerMinute(m.Turns, m.Duration)
+	return m
+}
+
+// percentile returns the nearest-rank percentile p of ds, or 0 if ds is
+// empty. ds is sorted in place.
+func percentile(ds []time.Duration, p float64) time.Duration {
+	if len(ds) == 0 {
+		return 0
+	}
+	sort.Slice(ds, func(i, j int) bool { return ds[i] < ds[j] })
+	rank := int(math.Ceil(p / 100 * float64(len(ds))))
+	if rank < 1 {
+		rank = 1
+	}
+	return ds[rank-1]
+}
+
+func turnsPerMinute(turns int, d time.Duration) float64 {
+	if d <= 0 {
+		return 0
+	}
+	return float64(turns) / d.Minutes()
+}
@@ -0,0 +1,365 @@
+package vapi
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Dimensions a CostAggregator can group by
+const (
+	CostByAssistant   = "assistant"
+	CostByPhoneNumber = "phoneNumber"
+	CostByProvider    = "provider"
+	CostByType        = "costType"
+)
+
+// CostBucket is the length of the time periods costs are grouped into
+type CostBucket string
+
+const (
+	BucketNone  CostBucket = ""
+	BucketHour  CostBucket = "hour"
+	BucketDay   CostBucket = "day"
+	BucketWeek  CostBucket = "week" // weeks start on Monday
+	BucketMonth CostBucket = "month"
+)
+
+// CostGroup is the spend of the calls sharing the same values of the
+// grouped-by dimensions. Dimensions that are not grouped by are empty.
+type CostGroup struct {
+	Assistant   string
+	PhoneNumber string
+	Provider    string
+	CostType    string
+	Period      time.Time // start of the time bucket, zero without one
+
+	Calls           int
+	SuccessfulCalls int
+	Minutes         float64
+	Cost            float64
+}
+
+// CostPerMinute is the cost divided by the minutes of the calls
+func (g CostGroup) CostPerMinute() float64 {
+	if g.Minutes == 0 {
+		return 0
+	}
+	return g.Cost / g.Minutes
+}
+
+// CostPerSuccessfulCall is the cost of all the calls divided by the number
+// that succeeded, so failed calls count toward the cost of success.
+func (g CostGroup) CostPerSuccessfulCall() float64 {
+	if g.SuccessfulCalls == 0 {
+		return 0
+	}
+	return g.Cost / float64(g.SuccessfulCalls)
+}
+
+// CostAggregator sums call costs into groups:
+//
+//	agg := vapi.NewCostAggregator(vapi.BucketDay, vapi.CostByAssistant, vapi.CostByType)
+//	agg.AddCalls(calls)
+//	agg.WriteCSV(os.Stdout)
+//
+// When grouping by provider or cost type, each entry of Call.Costs goes to
+// its own group, and calls without entries go to a group with an empty
+// provider and type; otherwise the call's total cost is used.
+type CostAggregator struct {
+	// Location sets the time zone time buckets are aligned to. The default
+	// is UTC.
+	Location *time.Location
+	// Succeeded decides which calls count as successful. The default is
+	// CallSucceeded.
+	Succeeded func(*Call) bool
+
+	groupBy []string
+	bucket  CostBucket
+	groups  map[CostGroup]*CostGroup
+	total   CostGroup
+}
+
+// NewCostAggregator returns an aggregator grouping by the given dimensions
+// and time bucket
+func NewCostAggregator(bucket CostBucket, groupBy ...string) *CostAggregator {
+	return &CostAggregator{groupBy: groupBy, bucket: bucket, groups: map[CostGroup]*CostGroup{}}
+}
+
+// AddCalls adds every call in calls
+func (a *CostAggregator) AddCalls(calls []Call) {
+	for i := range calls {
+		a.Add(&calls[i])
+	}
+}
+
+// AddSeq adds every call an iterator yields, so calls can be streamed from
+// a paginated listing without holding them all in memory.
+func (a *CostAggregator) AddSeq(seq func(yield func(*Call) bool)) {
+	seq(func(c *Call) bool {
+		a.Add(c)
+		return true
+	})
+}
+
+// Add adds one call
+func (a *CostAggregator) Add(c *Call) {
+	base := CostGroup{}
+	for _, dim := range a.groupBy {
+		switch dim {
+		case CostByAssistant:
+			base.Assistant = callAssistantKey(c)
+		case CostByPhoneNumber:
+			base.PhoneNumber = callPhoneNumberKey(c)
+		}
+	}
+	if a.bucket != BucketNone {
+		if t := callStartTime(c); t != nil {
+			base.Period = a.bucketStart(*t)
+		}
+	}
+
+	succeeded := CallSucceeded
+	if a.Succeeded != nil {
+		succeeded = a.Succeeded
+	}
+	success := succeeded(c)
+	minutes := callMinutes(c)
+	a.total.add(callTotalCost(c), minutes, success)
+
+	if len(c.Costs) == 0 || (!a.groupsBy(CostByProvider) && !a.groupsBy(CostByType)) {
+		a.add(base, callTotalCost(c), minutes, success)
+		return
+	}
+
+	// A call counts once in each group it has costs in
+	seen := map[CostGroup]bool{}
+	for _, cost := range c.Costs {
+		key := base
+		if a.groupsBy(CostByProvider) {
+			key.Provider = cost.Provider
+		}
+		if a.groupsBy(CostByType) {
+			key.CostType = cost.Type
+		}
+		if seen[key] {
+			a.groups[key].Cost += cost.Cost
+			continue
+		}
+		seen[key] = true
+		a.add(key, cost.Cost, minutes, success)
+	}
+}
+
+func (a *CostAggregator) add(key CostGroup, cost, minutes float64, success bool) {
+	g, ok := a.groups[key]
+	if !ok {
+		group := key
+		g = &group
+		a.groups[key] = g
+	}
+	g.add(cost, minutes, success)
+}
+
+func (g *CostGroup) add(cost, minutes float64, success bool) {
+	g.Calls++
+	g.Minutes += minutes
+	g.Cost += cost
+	if success {
+		g.SuccessfulCalls++
+	}
+}
+
+func (a *CostAggregator) groupsBy(dim string) bool {
+	return contains(a.groupBy, dim)
+}
+
+// bucketStart returns the start of the bucket t falls in
+func (a *CostAggregator) bucketStart(t time.Time) time.Time {
+	loc := a.Location
+	if loc == nil {
+		loc = time.UTC
+	}
+	t = t.In(loc)
+	switch a.bucket {
+	case BucketHour:
+		return time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), 0, 0, 0, loc)
+	case BucketDay:
+		return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, loc)
+	case BucketWeek:
+		offset := (int(t.Weekday()) + 6) % 7
+		return time.Date(t.Year(), t.Month(), t.Day()-offset, 0, 0, 0, 0, loc)
+	case BucketMonth:
+		return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, loc)
+	}
+	return time.Time{}
+}
+
+// Groups returns the groups ordered by period and then by the dimensions
+func (a *CostAggregator) Groups() []CostGroup {
+	groups := make([]CostGroup, 0, len(a.groups))
+	for _, g := range a.groups {
+		groups = append(groups, *g)
+	}
+	sort.Slice(groups, func(i, j int) bool {
+		gi, gj := groups[i], groups[j]
+		if !gi.Period.Equal(gj.Period) {
+			return gi.Period.Before(gj.Period)
+		}
+		ki := []string{gi.Assistant, gi.PhoneNumber, gi.Provider, gi.CostType}
+		kj := []string{gj.Assistant, gj.PhoneNumber, gj.Provider, gj.CostType}
+		for k := range ki {
+			if ki[k] != kj[k] {
+				return ki[k] < kj[k]
+			}
+		}
+		return false
+	})
+	return groups
+}
+
+// Total returns the sum over all calls, counting each call, its minutes and
+// its total cost once however many groups it is split across
+func (a *CostAggregator) Total() CostGroup {
+	return a.total
+}
+
+// WriteCSV writes one row per group, with a column for the time bucket and
+// each grouped-by dimension followed by the totals and derived costs.
+func (a *CostAggregator) WriteCSV(w io.Writer) error {
+	var header []string
+	if a.bucket != BucketNone {
+		header = append(header, "period")
+	}
+	header = append(header, a.groupBy...)
+	header = append(header, "calls", "successful_calls", "minutes", "cost", "cost_per_minute", "cost_per_successful_call")
+
+	cw := csv.NewWriter(w)
+	if err := cw.Write(header); err != nil {
+		return err
+	}
+	for _, g := range a.Groups() {
+		var row []string
+		if a.bucket != BucketNone {
+			period := ""
+			if !g.Period.IsZero() {
+				period = g.Period.Format(time.RFC3339)
+			}
+			row = append(row, period)
+		}
+		for _, dim := range a.groupBy {
+			switch dim {
+			case CostByAssistant:
+				row = append(row, g.Assistant)
+			case CostByPhoneNumber:
+				row = append(row, g.PhoneNumber)
+			case CostByProvider:
+				row = append(row, g.Provider)
+			case CostByType:
+				row = append(row, g.CostType)
+			default:
+				row = append(row, "")
+			}
+		}
+		row = append(row,
+			strconv.Itoa(g.Calls),
+			strconv.Itoa(g.SuccessfulCalls),
+			formatCostNumber(g.Minutes),
+			formatCostNumber(g.Cost),
+			formatCostNumber(g.CostPerMinute()),
+			formatCostNumber(g.CostPerSuccessfulCall()),
+		)
+		if err := cw.Write(row); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+func formatCostNumber(f float64) string {
+	return strconv.FormatFloat(f, 'f', 4, 64)
+}
+
+// CallSucceeded reports whether the call passed a PassFail success
+// evaluation. Calls without one are not successful.
+func CallSucceeded(c *Call) bool {
+	passed, err := c.Analysis.PassFail()
+	return err == nil && passed
+}
+
+func callAssistantKey(c *Call) string {
+	switch {
+	case c.AssistantID != nil:
+		return *c.AssistantID
+	case c.Assistant != nil && c.Assistant.Name != nil:
+		return *c.Assistant.Name
+	}
+	return ""
+}
+
+func callPhoneNumberKey(c *Call) string {
+	switch {
+	case c.PhoneNumberID != nil:
+		return *c.PhoneNumberID
+	case c.PhoneNumber != nil && c.PhoneNumber.TwilioPhoneNumber != "":
+		return c.PhoneNumber.TwilioPhoneNumber
+	}
+	return ""
+}
+
+func callStartTime(c *Call) *time.Time {
+	if c.StartedAt != nil {
+		return c.StartedAt
+	}
+	return c.CreatedAt
+}
+
+// callMinutes is the length of the call, from its timestamps or else from
+// the minutes billed in its costs
+func callMinutes(c *Call) float64 {
+	if c.StartedAt != nil && c.EndedAt != nil && c.EndedAt.After(*c.StartedAt) {
+		return c.EndedAt.Sub(*c.StartedAt).Minutes()
+	}
+	var minutes float64
+	for _, cost := range c.Costs {
+		if cost.Minutes > minutes {
+			minutes = cost.Minutes
+		}
+	}
+	return minutes
+}
+
+// callTotalCost is the call's cost, falling back to the breakdown total and
+// then to the sum of the cost entries
+func callTotalCost(c *Call) float64 {
+	switch {
+	case c.Cost != nil:
+		return *c.Cost
+	case c.CostBreakdown != nil && c.CostBreakdown.Total != 0:
+		return c.CostBreakdown.Total
+	}
+	var total float64
+	for _, cost := range c.Costs {
+		total += cost.Cost
+	}
+	return total
+}
+
+// String formats the group's totals for logs
+func (g CostGroup) String() string {
+	var keys []string
+	for _, k := range []string{g.Assistant, g.PhoneNumber, g.Provider, g.CostType} {
+		if k != "" {
+			keys = append(keys, k)
+		}
+	}
+	if !g.Period.IsZero() {
+		keys = append([]string{g.Period.Format("2006-01-02T15:04")}, keys...)
+	}
+	return fmt.Sprintf("%s: %d calls, %.1f min, $%.4f ($%.4f/min)", strings.Join(keys, " "), g.Calls, g.Minutes, g.Cost, g.CostPerMinute())
+}
@@ -0,0 +1,103 @@
+package vapi
+
+import (
+	"encoding/json"
+	"math"
+	"strings"
+	"testing"
+	"time"
+)
+
+func costTestCall(assistant string, start time.Time, minutes float64, success any, costs ...Cost) Call {
+	end := start.Add(time.Duration(minutes * float64(time.Minute)))
+	total := 0.0
+	for _, c := range costs {
+		total += c.Cost
+	}
+	return Call{
+		AssistantID: &assistant,
+		StartedAt:   &start,
+		EndedAt:     &end,
+		Cost:        &total,
+		Costs:       costs,
+		Analysis:    &Analysis{SuccessEvaluation: success},
+	}
+}
+
+func TestCostAggregator(t *testing.T) {
+	day1 := time.Date(2024, 5, 1, 9, 30, 0, 0, time.UTC)
+	day2 := day1.Add(24 * time.Hour)
+	calls := []Call{
+		costTestCall("asst-a", day1, 2, true,
+			Cost{Type: "transport", Provider: "twilio", Cost: 0.02},
+			Cost{Type: "model", Provider: "openai", Cost: 0.10}),
+		costTestCall("asst-a", day1.Add(time.Hour), 3, "false",
+			Cost{Type: "transport", Provider: "twilio", Cost: 0.03},
+			Cost{Type: "model", Provider: "openai", Cost: 0.15}),
+		costTestCall("asst-b", day2, 1, "Pass",
+			Cost{Type: "model", Provider: "anthropic", Cost: 0.05}),
+	}
+
+	agg := NewCostAggregator(BucketDay, CostByAssistant)
+	agg.AddCalls(calls)
+	groups := agg.Groups()
+	if len(groups) != 2 {
+		t.Fatalf("got %d groups, want 2: %v", len(groups), groups)
+	}
+	a := groups[0]
+	if a.Assistant != "asst-a" || !a.Period.Equal(time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("first group = %v", a)
+	}
+	if a.Calls != 2 || a.SuccessfulCalls != 1 || a.Minutes != 5 || math.Abs(a.Cost-0.30) > 1e-9 {
+		t.Errorf("first group totals = %+v", a)
+	}
+	if math.Abs(a.CostPerMinute()-0.06) > 1e-9 || math.Abs(a.CostPerSuccessfulCall()-0.30) > 1e-9 {
+		t.Errorf("per minute %f, per success %f", a.CostPerMinute(), a.CostPerSuccessfulCall())
+	}
+
+	byProvider := NewCostAggregator(BucketNone, CostByProvider)
+	seq := func(yield func(*Call) bool) {
+		for i := range calls {
+			if !yield(&calls[i]) {
+				return
+			}
+		}
+	}
+	byProvider.AddSeq(seq)
+	var csv strings.Builder
+	if err := byProvider.WriteCSV(&csv); err != nil {
+		t.Fatal(err)
+	}
+	want := `provider,calls,successful_calls,minutes,cost,cost_per_minute,cost_per_successful_call
+anthropic,1,1,1.0000,0.0500,0.0500,0.0500
+openai,2,1,5.0000,0.2500,0.0500,0.2500
+twilio,2,1,5.0000,0.0500,0.0100,0.0500
+`
+	if csv.String() != want {
+		t.Errorf("CSV =\n%s\nwant\n%s", csv.String(), want)
+	}
+	if total := byProvider.Total(); math.Abs(total.Cost-0.35) > 1e-9 || total.Calls != 3 || total.Minutes != 6 {
+		t.Errorf("total = %+v, want 3 calls, 6 minutes, $0.35", total)
+	}
+
+	// A call without cost entries still counts, in a group of its own
+	costless := costTestCall("asst-c", day2, 4, true)
+	byProvider.Add(&costless)
+	if groups := byProvider.Groups(); groups[0].Provider != "" || groups[0].Calls != 1 || groups[0].Minutes != 4 {
+		t.Errorf("costless call group = %+v", groups[0])
+	}
+	if total := byProvider.Total(); total.Calls != 4 || total.Minutes != 10 {
+		t.Errorf("total with costless call = %+v", total)
+	}
+}
+
+func TestCostBreakdown_AnalysisTokens(t *testing.T) {
+	var b CostBreakdown
+	data := `{"total": 0.4, "analysisCostBreakdown": {"summary": 0.01, "summaryPromptTokens": 812, "summaryCompletionTokens": 64}}`
+	if err := json.Unmarshal([]byte(data), &b); err != nil {
+		t.Fatal(err)
+	}
+	if b.AnalysisCostBreakdown.SummaryPromptTokens != 812 || b.AnalysisCostBreakdown.SummaryCompletionTokens != 64 {
+		t.Errorf("analysis breakdown = %+v", b.AnalysisCostBreakdown)
+	}
+}
@@ -0,0 +1,128 @@
+package vapi
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+)
+
+// FieldChange describes one field that differs between two values. Path uses
+// the JSON field names, e.g. "model.messages[0].content". Old or New is nil
+// when the field is absent on that side.
+type FieldChange struct {
+	Path string
+	Old  any
+	New  any
+}
+
+func (c FieldChange) String() string {
+	return fmt.Sprintf("%s: %s -> %s", c.Path, formatChangeValue(c.Old), formatChangeValue(c.New))
+}
+
+func formatChangeValue(v any) string {
+	if v == nil {
+		return "<unset>"
+	}
+	b, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprint(v)
+	}
+	return string(b)
+}
+
+// toJSONValue converts v to its generic JSON form (maps, slices, float64, ...)
+// so values of different Go types can be compared field by field.
+func toJSONValue(v any) (any, error) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	var out any
+	if err := json.Unmarshal(b, &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// diffJSONValues appends the differences between old and new to changes. When
+// subset is true, object keys missing from new are ignored, which is how a
+// desired state is compared against a server copy full of defaults.
+func diffJSONValues(path string, old, new any, subset bool, changes *[]FieldChange) {
+	switch n := new.(type) {
+	case map[string]any:
+		o, ok := old.(map[string]any)
+		if !ok {
+			break
+		}
+		keys := make([]string, 0, len(n)+len(o))
+		for k := range n {
+			keys = append(keys, k)
+		}
+		if !subset {
+			for k := range o {
+				if _, ok := n[k]; !ok {
+					keys = append(keys, k)
+				}
+			}
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			diffJSONValues(joinPath(path, k), o[k], n[k], subset, changes)
+		}
+		return
+	case []any:
+		o, ok := old.([]any)
+		if !ok {
+			break
+		}
+		size := len(n)
+		if len(o) > size {
+			size = len(o)
+		}
+		for i := 0; i < size; i++ {
+			var ov, nv any
+			if i < len(o) {
+				ov = o[i]
+			}
+			if i < len(n) {
+				nv = n[i]
+			}
+			diffJSONValues(fmt.Sprintf("%s[%d]", path, i), ov, nv, subset, changes)
+		}
+		return
+	}
+
+	if !reflect.DeepEqual(old, new) {
+		*changes = append(*changes, FieldChange{Path: path, Old: old, New: new})
+	}
+}
+
+func joinPath(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
+
+// DiffAssistants returns every field that differs between a and b, with the
+// value from a as Old and the value from b as New. Paths use the JSON field
+// names, so a changed system prompt shows up as "model.messages[0].content".
+func DiffAssistants(a, b *Assistant) ([]FieldChange, error) {
+	var oldValue, newValue any = map[string]any{}, map[string]any{}
+	var err error
+	if a != nil {
+		if oldValue, err = toJSONValue(a); err != nil {
+			return nil, err
+		}
+	}
+	if b != nil {
+		if newValue, err = toJSONValue(b); err != nil {
+			return nil, err
+		}
+	}
+
+	var changes []FieldChange
+	diffJSONValues("", oldValue, newValue, false, &changes)
+	return changes, nil
+}
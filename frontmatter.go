@@ -0,0 +1,334 @@
+package vapi
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v2"
+)
+
+// Front matter formats a prompt file header can use. The header must start
+// on the first line of the file:
+//
+//	---            +++              ---json
+//	name: intro    name = "intro"   { "name": "intro" }
+//	---            +++              ---
+const (
+	FrontMatterYAML = "yaml"
+	FrontMatterTOML = "toml"
+	FrontMatterJSON = "json"
+)
+
+const utf8BOM = "\uFEFF"
+
+// FrontMatterError reports a malformed prompt file header. Line is 1-based
+// and counts from the top of the file.
+type FrontMatterError struct {
+	Line int
+	Msg  string
+}
+
+func (e *FrontMatterError) Error() string {
+	return fmt.Sprintf("front matter: line %d: %s", e.Line, e.Msg)
+}
+
+// frontMatter is a prompt file split into its header and body. Joining
+// head, raw, tail and rest gives back the (normalized) file.
+type frontMatter struct {
+	format string // empty when the file has no header
+	head   string // opening delimiter line
+	raw    string // header content
+	tail   string // closing delimiter line
+	rest   string // everything after the header
+
+	// headerLine is the file line the header content starts on
+	headerLine int
+}
+
+// parseFrontMatter splits content into header and body. A UTF-8 BOM is
+// ignored and CRLF line endings are treated as LF.
+func parseFrontMatter(content string) (*frontMatter, error) {
+	content = strings.TrimPrefix(content, utf8BOM)
+	content = strings.ReplaceAll(content, "\r\n", "\n")
+
+	firstLine := content
+	if i := strings.IndexByte(content, '\n'); i >= 0 {
+		firstLine = content[:i]
+	}
+	delim := strings.TrimRight(firstLine, " \t")
+
+	switch {
+	case delim == "---" || delim == "+++" || delim == "---json":
+		format, closing := FrontMatterYAML, delim
+		switch delim {
+		case "+++":
+			format = FrontMatterTOML
+		case "---json":
+			format, closing = FrontMatterJSON, "---"
+		}
+		if len(firstLine) == len(content) {
+			return nil, &FrontMatterError{Line: 1, Msg: "unclosed front matter, missing closing " + closing}
+		}
+
+		lines := strings.SplitAfter(content[len(firstLine)+1:], "\n")
+		offset := len(firstLine) + 1
+		for i, line := range lines {
+			trimmed := strings.TrimRight(line, " \t\n")
+			if trimmed == closing || (format == FrontMatterYAML && trimmed == "...") {
+				return &frontMatter{
+					format:     format,
+					head:       content[:len(firstLine)+1],
+					raw:        content[len(firstLine)+1 : offset],
+					tail:       line,
+					rest:       content[offset+len(line):],
+					headerLine: 2,
+				}, nil
+			}
+			if i == len(lines)-1 {
+				break
+			}
+			offset += len(line)
+		}
+		return nil, &FrontMatterError{Line: 1, Msg: "unclosed front matter, missing closing " + closing}
+
+	case strings.HasPrefix(delim, "---") && strings.Trim(delim, "-") == "":
+		// the user put too many dashes or it was not the right format
+		return nil, &FrontMatterError{Line: 1, Msg: fmt.Sprintf("invalid delimiter %q, use ---", delim)}
+	}
+
+	return &frontMatter{rest: content}, nil
+}
+
+// lineAt returns the 1-based line of the byte offset in s
+func lineAt(s string, offset int) int {
+	if offset > len(s) {
+		offset = len(s)
+	}
+	return strings.Count(s[:offset], "\n") + 1
+}
+
+var yamlErrorLine = regexp.MustCompile(`line (\d+)`)
+
+// decodeHeader decodes the header into a PromptHeader. Errors carry the line
+// number within the file.
+func (fm *frontMatter) decodeHeader() (PromptHeader, error) {
+	var header PromptHeader
+
+	switch fm.format {
+	case "":
+		return header, nil
+	case FrontMatterYAML:
+		if err := yaml.Unmarshal([]byte(fm.raw), &header); err != nil {
+			line := fm.headerLine
+			if m := yamlErrorLine.FindStringSubmatch(err.Error()); m != nil {
+				n, _ := strconv.Atoi(m[1])
+				line += n - 1
+			}
+			return header, &FrontMatterError{Line: line, Msg: err.Error()}
+		}
+		return header, nil
+	}
+
+	var fields map[string]any
+	switch fm.format {
+	case FrontMatterTOML:
+		if _, err := toml.Decode(fm.raw, &fields); err != nil {
+			line := fm.headerLine
+			var perr toml.ParseError
+			if errors.As(err, &perr) {
+				line += perr.Position.Line - 1
+			}
+			return header, &FrontMatterError{Line: line, Msg: err.Error()}
+		}
+	case FrontMatterJSON:
+		if err := json.Unmarshal([]byte(fm.raw), &fields); err != nil {
+			line := fm.headerLine
+			var syntax *json.SyntaxError
+			if errors.As(err, &syntax) {
+				line += lineAt(fm.raw, int(syntax.Offset)) - 1
+			}
+			return header, &FrontMatterError{Line: line, Msg: err.Error()}
+		}
+	}
+
+	// Go through YAML so all formats share the PromptHeader field mapping
+	b, err := yaml.Marshal(fields)
+	if err != nil {
+		return header, err
+	}
+	if err := yaml.Unmarshal(b, &header); err != nil {
+		return header, &FrontMatterError{Line: fm.headerLine, Msg: err.Error()}
+	}
+	return header, nil
+}
+
+// body returns the prompt body with surrounding whitespace removed, and
+// the file line it starts on. Files without a header are returned whole.
+func (fm *frontMatter) body() (string, int) {
+	if fm.format == "" {
+		return fm.rest, 1
+	}
+	start := fm.headerLine + strings.Count(fm.raw+fm.tail, "\n")
+	trimmed := strings.TrimLeft(fm.rest, " \t\r\n")
+	start += strings.Count(fm.rest[:len(fm.rest)-len(trimmed)], "\n")
+	return strings.TrimSpace(trimmed), start
+}
+
+// setField sets a top-level header key. Only the line holding the key is
+// rewritten, so comments, key order and nested keys of the same name are
+// left as they are.
+func (fm *frontMatter) setField(key, value string) error {
+	switch fm.format {
+	case "", FrontMatterYAML:
+		raw, err := setYAMLField(fm.raw, key, value)
+		if err != nil {
+			return err
+		}
+		if fm.format == "" {
+			fm.format = FrontMatterYAML
+			fm.head, fm.tail = "---\n", "---\n"
+			fm.rest = "\n" + fm.rest
+		}
+		fm.raw = raw
+
+	case FrontMatterTOML:
+		// Only keys before the first [table] are top-level
+		top, tables := fm.raw, ""
+		if loc := regexp.MustCompile(`(?m)^[ \t]*\[`).FindStringIndex(fm.raw); loc != nil {
+			top, tables = fm.raw[:loc[0]], fm.raw[loc[0]:]
+		}
+		line := fmt.Sprintf("%s = %s", key, strconv.Quote(value))
+		pattern := regexp.MustCompile(`(?m)^[ \t]*` + regexp.QuoteMeta(key) + `[ \t]*=.*$`)
+		if pattern.MatchString(top) {
+			top = pattern.ReplaceAllLiteralString(top, line)
+		} else {
+			top += line + "\n"
+		}
+		fm.raw = top + tables
+
+	case FrontMatterJSON:
+		raw, err := setJSONField(fm.raw, key, value)
+		if err != nil {
+			return err
+		}
+		fm.raw = raw
+	}
+	return nil
+}
+
+// setYAMLField replaces the value of the unindented key line in raw, along
+// with any lines continuing it, or appends the key when it is missing. A
+// comment after a single-line value is kept.
+func setYAMLField(raw, key, value string) (string, error) {
+	out, err := yaml.Marshal(value)
+	if err != nil {
+		return "", err
+	}
+	scalar := strings.TrimSuffix(string(out), "\n")
+
+	pattern := regexp.MustCompile(`^(` + regexp.QuoteMeta(key) + `[ \t]*:)(?:[ \t]*("(?:[^"\\]|\\.)*"|'(?:[^']|'')*'|[^#\s][^#]*?))?([ \t]+#.*)?[ \t]*$`)
+	lines := strings.SplitAfter(raw, "\n")
+	for i, line := range lines {
+		m := pattern.FindStringSubmatch(strings.TrimSuffix(line, "\n"))
+		if m == nil {
+			continue
+		}
+		end := i + 1
+		for end < len(lines) && (strings.HasPrefix(lines[end], " ") || strings.HasPrefix(lines[end], "\t") || strings.HasPrefix(lines[end], "- ")) {
+			end++
+		}
+		lines[i] = m[1] + " " + scalar + m[3] + "\n"
+		raw = strings.Join(lines[:i+1], "") + strings.Join(lines[end:], "")
+		return raw, checkYAMLHeader(raw, key)
+	}
+
+	if raw != "" && !strings.HasSuffix(raw, "\n") {
+		raw += "\n"
+	}
+	raw += key + ": " + scalar + "\n"
+	return raw, checkYAMLHeader(raw, key)
+}
+
+func checkYAMLHeader(raw, key string) error {
+	var fields yaml.MapSlice
+	if err := yaml.Unmarshal([]byte(raw), &fields); err != nil {
+		return fmt.Errorf("failed to update %q in YAML front matter: %w", key, err)
+	}
+	return nil
+}
+
+// setJSONField replaces the value of key in the top-level object of raw, or
+// adds the key first when it is missing.
+func setJSONField(raw, key, value string) (string, error) {
+	quoted, err := json.Marshal(value)
+	if err != nil {
+		return "", err
+	}
+
+	dec := json.NewDecoder(strings.NewReader(raw))
+	depth, isKey := 0, false
+	for {
+		tok, err := dec.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return "", fmt.Errorf("failed to update %q in JSON front matter: %w", key, err)
+		}
+
+		if depth == 1 && isKey {
+			isKey = false
+			if tok != key {
+				continue
+			}
+			start := int(dec.InputOffset())
+			start += len(raw[start:]) - len(strings.TrimLeft(raw[start:], " \t\r\n:"))
+			tok, err := dec.Token()
+			if err != nil {
+				return "", fmt.Errorf("failed to update %q in JSON front matter: %w", key, err)
+			}
+			if _, ok := tok.(json.Delim); ok {
+				return "", fmt.Errorf("failed to update %q in JSON front matter: value is not a scalar", key)
+			}
+			end := int(dec.InputOffset())
+			return raw[:start] + string(quoted) + raw[end:], nil
+		}
+
+		switch tok {
+		case json.Delim('{'), json.Delim('['):
+			depth++
+			isKey = depth == 1 && tok == json.Delim('{')
+		case json.Delim('}'), json.Delim(']'):
+			depth--
+			isKey = depth == 1
+		default:
+			isKey = depth == 1
+		}
+	}
+
+	i := strings.IndexByte(raw, '{')
+	if i < 0 {
+		return "", fmt.Errorf("failed to update %q in JSON front matter: no object", key)
+	}
+	sep := ","
+	if strings.TrimSpace(raw[i+1:]) == "}" {
+		sep = ""
+	}
+	return raw[:i+1] + fmt.Sprintf("\n  %q: %s%s", key, quoted, sep) + raw[i+1:], nil
+}
+
+func (fm *frontMatter) String() string {
+	var b bytes.Buffer
+	b.WriteString(fm.head)
+	b.WriteString(fm.raw)
+	b.WriteString(fm.tail)
+	b.WriteString(fm.rest)
+	return b.String()
+}
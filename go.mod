@@ -5,6 +5,7 @@ go 1.18
 // replace github.com/chriscow/minds => ../thoughtnet/minds
 
 require (
+	github.com/BurntSushi/toml v1.4.0
 	github.com/chriscow/minds v0.0.7
 	github.com/sashabaranov/go-openai v1.39.1
 	gopkg.in/yaml.v2 v2.4.0
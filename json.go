@@ -0,0 +1,737 @@
+package vapi
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// StrictDecoding makes the API functions fail when a response contains fields
+// the structs in this package do not model. It is meant for tests and CI jobs
+// that watch for Vapi API drift.
+var StrictDecoding = false
+
+// UnknownFieldsError lists the JSON paths of fields that were not recognized
+// during strict decoding.
+type UnknownFieldsError struct {
+	Fields []string
+}
+
+func (e *UnknownFieldsError) Error() string {
+	return fmt.Sprintf("unknown fields: %s", strings.Join(e.Fields, ", "))
+}
+
+// DecodeStrict unmarshals data into v and returns an *UnknownFieldsError if
+// any field ended up in an Extra map. v is still populated in that case.
+func DecodeStrict(data []byte, v any) error {
+	if err := json.Unmarshal(data, v); err != nil {
+		return err
+	}
+	if fields := UnknownFields(v); len(fields) > 0 {
+		return &UnknownFieldsError{Fields: fields}
+	}
+	return nil
+}
+
+// decodeResponse unmarshals an API response, honoring StrictDecoding
+func decodeResponse(data []byte, v any) error {
+	if StrictDecoding {
+		return DecodeStrict(data, v)
+	}
+	return json.Unmarshal(data, v)
+}
+
+// UnknownFields walks v and returns the JSON path of every field held in an
+// Extra map, e.g. "artifact.messages[3].toolCalls".
+func UnknownFields(v any) []string {
+	var fields []string
+	collectUnknownFields(reflect.ValueOf(v), "", &fields)
+	sort.Strings(fields)
+	return fields
+}
+
+var rawMapType = reflect.TypeOf(map[string]json.RawMessage{})
+
+func collectUnknownFields(v reflect.Value, path string, fields *[]string) {
+	switch v.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		if !v.IsNil() {
+			collectUnknownFields(v.Elem(), path, fields)
+		}
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			collectUnknownFields(v.Index(i), fmt.Sprintf("%s[%d]", path, i), fields)
+		}
+	case reflect.Map:
+		if v.Type() == rawMapType {
+			return
+		}
+		for _, k := range v.MapKeys() {
+			collectUnknownFields(v.MapIndex(k), joinPath(path, fmt.Sprint(k.Interface())), fields)
+		}
+	case reflect.Struct:
+		t := v.Type()
+		for i := 0; i < t.NumField(); i++ {
+			f := t.Field(i)
+			if f.Anonymous && f.Tag.Get("json") == "" {
+				collectUnknownFields(v.Field(i), path, fields)
+				continue
+			}
+			if !f.IsExported() {
+				continue
+			}
+			if f.Name == "Extra" && f.Type == rawMapType {
+				for k := range v.Field(i).Interface().(map[string]json.RawMessage) {
+					*fields = append(*fields, joinPath(path, k))
+				}
+				continue
+			}
+			name := strings.Split(f.Tag.Get("json"), ",")[0]
+			if name == "-" {
+				continue
+			}
+			if name == "" {
+				name = f.Name
+			}
+			collectUnknownFields(v.Field(i), joinPath(path, name), fields)
+		}
+	}
+}
+
+// knownFields caches the lower-cased JSON names of each struct type's fields.
+// encoding/json matches names case-insensitively, so lookups do too.
+var knownFields sync.Map
+
+func jsonFieldNames(t reflect.Type) map[string]bool {
+	if names, ok := knownFields.Load(t); ok {
+		return names.(map[string]bool)
+	}
+
+	names := map[string]bool{}
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		name := strings.Split(f.Tag.Get("json"), ",")[0]
+		if name == "" && f.Anonymous {
+			// Fields of untagged embedded structs are promoted
+			ft := f.Type
+			if ft.Kind() == reflect.Ptr {
+				ft = ft.Elem()
+			}
+			if ft.Kind() == reflect.Struct {
+				for k := range jsonFieldNames(ft) {
+					names[k] = true
+				}
+				continue
+			}
+		}
+		if name == "-" || !f.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		names[strings.ToLower(name)] = true
+	}
+	knownFields.Store(t, names)
+	return names
+}
+
+// unmarshalWithExtra decodes data into target, a pointer to an alias of the
+// struct, and stores any fields target does not know about in extra.
+func unmarshalWithExtra(data []byte, target any, extra *map[string]json.RawMessage) error {
+	if err := json.Unmarshal(data, target); err != nil {
+		return err
+	}
+	*extra = nil
+
+	if !bytes.HasPrefix(bytes.TrimSpace(data), []byte("{")) {
+		return nil
+	}
+	var all map[string]json.RawMessage
+	if err := json.Unmarshal(data, &all); err != nil {
+		return err
+	}
+
+	known := jsonFieldNames(reflect.TypeOf(target).Elem())
+	for k, v := range all {
+		if known[strings.ToLower(k)] {
+			continue
+		}
+		if *extra == nil {
+			*extra = map[string]json.RawMessage{}
+		}
+		(*extra)[k] = v
+	}
+	return nil
+}
+
+// marshalWithExtra encodes v, an alias of the struct, and adds the fields
+// from extra back in.
+func marshalWithExtra(v any, extra map[string]json.RawMessage) ([]byte, error) {
+	b, err := json.Marshal(v)
+	if err != nil || len(extra) == 0 {
+		return b, err
+	}
+
+	var all map[string]json.RawMessage
+	if err := json.Unmarshal(b, &all); err != nil {
+		return nil, err
+	}
+	for k, raw := range extra {
+		if _, ok := all[k]; !ok {
+			all[k] = raw
+		}
+	}
+	return json.Marshal(all)
+}
+
+func (a *Assistant) UnmarshalJSON(data []byte) error {
+	type alias Assistant
+	return unmarshalWithExtra(data, (*alias)(a), &a.Extra)
+}
+
+func (a Assistant) MarshalJSON() ([]byte, error) {
+	type alias Assistant
+	return marshalWithExtra(alias(a), a.Extra)
+}
+
+func (c *ModelConfig) UnmarshalJSON(data []byte) error {
+	type alias ModelConfig
+	return unmarshalWithExtra(data, (*alias)(c), &c.Extra)
+}
+
+func (c ModelConfig) MarshalJSON() ([]byte, error) {
+	type alias ModelConfig
+	return marshalWithExtra(alias(c), c.Extra)
+}
+
+func (c *ElevenLabsVoiceConfig) UnmarshalJSON(data []byte) error {
+	type alias ElevenLabsVoiceConfig
+	return unmarshalWithExtra(data, (*alias)(c), &c.Extra)
+}
+
+func (c ElevenLabsVoiceConfig) MarshalJSON() ([]byte, error) {
+	type alias ElevenLabsVoiceConfig
+	return marshalWithExtra(alias(c), c.Extra)
+}
+
+func (c *TranscriberConfig) UnmarshalJSON(data []byte) error {
+	type alias TranscriberConfig
+	return unmarshalWithExtra(data, (*alias)(c), &c.Extra)
+}
+
+func (c TranscriberConfig) MarshalJSON() ([]byte, error) {
+	type alias TranscriberConfig
+	return marshalWithExtra(alias(c), c.Extra)
+}
+
+func (p *AnalysisPlan) UnmarshalJSON(data []byte) error {
+	type alias AnalysisPlan
+	return unmarshalWithExtra(data, (*alias)(p), &p.Extra)
+}
+
+func (p AnalysisPlan) MarshalJSON() ([]byte, error) {
+	type alias AnalysisPlan
+	return marshalWithExtra(alias(p), p.Extra)
+}
+
+func (t *Tool) UnmarshalJSON(data []byte) error {
+	type alias Tool
+	return unmarshalWithExtra(data, (*alias)(t), &t.Extra)
+}
+
+func (t Tool) MarshalJSON() ([]byte, error) {
+	type alias Tool
+	return marshalWithExtra(alias(t), t.Extra)
+}
+
+func (s *Squad) UnmarshalJSON(data []byte) error {
+	type alias Squad
+	return unmarshalWithExtra(data, (*alias)(s), &s.Extra)
+}
+
+func (s Squad) MarshalJSON() ([]byte, error) {
+	type alias Squad
+	return marshalWithExtra(alias(s), s.Extra)
+}
+
+func (c *Call) UnmarshalJSON(data []byte) error {
+	type alias Call
+	return unmarshalWithExtra(data, (*alias)(c), &c.Extra)
+}
+
+func (c Call) MarshalJSON() ([]byte, error) {
+	type alias Call
+	return marshalWithExtra(alias(c), c.Extra)
+}
+
+func (a *Artifact) UnmarshalJSON(data []byte) error {
+	type alias Artifact
+	return unmarshalWithExtra(data, (*alias)(a), &a.Extra)
+}
+
+func (a Artifact) MarshalJSON() ([]byte, error) {
+	type alias Artifact
+	return marshalWithExtra(alias(a), a.Extra)
+}
+
+func (a *Analysis) UnmarshalJSON(data []byte) error {
+	type alias Analysis
+	return unmarshalWithExtra(data, (*alias)(a), &a.Extra)
+}
+
+func (a Analysis) MarshalJSON() ([]byte, error) {
+	type alias Analysis
+	return marshalWithExtra(alias(a), a.Extra)
+}
+
+func (m *Message) UnmarshalJSON(data []byte) error {
+	type alias Message
+	return unmarshalWithExtra(data, (*alias)(m), &m.Extra)
+}
+
+func (m Message) MarshalJSON() ([]byte, error) {
+	type alias Message
+	return marshalWithExtra(alias(m), m.Extra)
+}
+
+func (c *Customer) UnmarshalJSON(data []byte) error {
+	type alias Customer
+	return unmarshalWithExtra(data, (*alias)(c), &c.Extra)
+}
+
+func (c Customer) MarshalJSON() ([]byte, error) {
+	type alias Customer
+	return marshalWithExtra(alias(c), c.Extra)
+}
+
+func (p *PhoneNumber) UnmarshalJSON(data []byte) error {
+	type alias PhoneNumber
+	return unmarshalWithExtra(data, (*alias)(p), &p.Extra)
+}
+
+func (p PhoneNumber) MarshalJSON() ([]byte, error) {
+	type alias PhoneNumber
+	return marshalWithExtra(alias(p), p.Extra)
+}
+
+func (v *VoicemailConfig) UnmarshalJSON(data []byte) error {
+	type alias VoicemailConfig
+	return unmarshalWithExtra(data, (*alias)(v), &v.Extra)
+}
+
+func (v VoicemailConfig) MarshalJSON() ([]byte, error) {
+	type alias VoicemailConfig
+	return marshalWithExtra(alias(v), v.Extra)
+}
+
+func (t *TranscriptionEndpointingPlan) UnmarshalJSON(data []byte) error {
+	type alias TranscriptionEndpointingPlan
+	return unmarshalWithExtra(data, (*alias)(t), &t.Extra)
+}
+
+func (t TranscriptionEndpointingPlan) MarshalJSON() ([]byte, error) {
+	type alias TranscriptionEndpointingPlan
+	return marshalWithExtra(alias(t), t.Extra)
+}
+
+func (s *SpeakingPlan) UnmarshalJSON(data []byte) error {
+	type alias SpeakingPlan
+	return unmarshalWithExtra(data, (*alias)(s), &s.Extra)
+}
+
+func (s SpeakingPlan) MarshalJSON() ([]byte, error) {
+	type alias SpeakingPlan
+	return marshalWithExtra(alias(s), s.Extra)
+}
+
+func (s *SchemaProperty) UnmarshalJSON(data []byte) error {
+	type alias SchemaProperty
+	return unmarshalWithExtra(data, (*alias)(s), &s.Extra)
+}
+
+func (s SchemaProperty) MarshalJSON() ([]byte, error) {
+	type alias SchemaProperty
+	return marshalWithExtra(alias(s), s.Extra)
+}
+
+func (s *SummaryPlan) UnmarshalJSON(data []byte) error {
+	type alias SummaryPlan
+	return unmarshalWithExtra(data, (*alias)(s), &s.Extra)
+}
+
+func (s SummaryPlan) MarshalJSON() ([]byte, error) {
+	type alias SummaryPlan
+	return marshalWithExtra(alias(s), s.Extra)
+}
+
+func (s *StructuredDataPlan) UnmarshalJSON(data []byte) error {
+	type alias StructuredDataPlan
+	return unmarshalWithExtra(data, (*alias)(s), &s.Extra)
+}
+
+func (s StructuredDataPlan) MarshalJSON() ([]byte, error) {
+	type alias StructuredDataPlan
+	return marshalWithExtra(alias(s), s.Extra)
+}
+
+func (s *StructuredDataMulti) UnmarshalJSON(data []byte) error {
+	type alias StructuredDataMulti
+	return unmarshalWithExtra(data, (*alias)(s), &s.Extra)
+}
+
+func (s StructuredDataMulti) MarshalJSON() ([]byte, error) {
+	type alias StructuredDataMulti
+	return marshalWithExtra(alias(s), s.Extra)
+}
+
+func (s *SuccessEvaluationPlan) UnmarshalJSON(data []byte) error {
+	type alias SuccessEvaluationPlan
+	return unmarshalWithExtra(data, (*alias)(s), &s.Extra)
+}
+
+func (s SuccessEvaluationPlan) MarshalJSON() ([]byte, error) {
+	type alias SuccessEvaluationPlan
+	return marshalWithExtra(alias(s), s.Extra)
+}
+
+func (m *ModelMessage) UnmarshalJSON(data []byte) error {
+	type alias ModelMessage
+	return unmarshalWithExtra(data, (*alias)(m), &m.Extra)
+}
+
+func (m ModelMessage) MarshalJSON() ([]byte, error) {
+	type alias ModelMessage
+	return marshalWithExtra(alias(m), m.Extra)
+}
+
+func (s *ServerConfig) UnmarshalJSON(data []byte) error {
+	type alias ServerConfig
+	return unmarshalWithExtra(data, (*alias)(s), &s.Extra)
+}
+
+func (s ServerConfig) MarshalJSON() ([]byte, error) {
+	type alias ServerConfig
+	return marshalWithExtra(alias(s), s.Extra)
+}
+
+func (v *Voice) UnmarshalJSON(data []byte) error {
+	type alias Voice
+	return unmarshalWithExtra(data, (*alias)(v), &v.Extra)
+}
+
+func (v Voice) MarshalJSON() ([]byte, error) {
+	type alias Voice
+	return marshalWithExtra(alias(v), v.Extra)
+}
+
+func (m *Model) UnmarshalJSON(data []byte) error {
+	type alias Model
+	return unmarshalWithExtra(data, (*alias)(m), &m.Extra)
+}
+
+func (m Model) MarshalJSON() ([]byte, error) {
+	type alias Model
+	return marshalWithExtra(alias(m), m.Extra)
+}
+
+func (t *Transcriber) UnmarshalJSON(data []byte) error {
+	type alias Transcriber
+	return unmarshalWithExtra(data, (*alias)(t), &t.Extra)
+}
+
+func (t Transcriber) MarshalJSON() ([]byte, error) {
+	type alias Transcriber
+	return marshalWithExtra(alias(t), t.Extra)
+}
+
+func (v *VoicemailDetection) UnmarshalJSON(data []byte) error {
+	type alias VoicemailDetection
+	return unmarshalWithExtra(data, (*alias)(v), &v.Extra)
+}
+
+func (v VoicemailDetection) MarshalJSON() ([]byte, error) {
+	type alias VoicemailDetection
+	return marshalWithExtra(alias(v), v.Extra)
+}
+
+func (k *KnowledgeBase) UnmarshalJSON(data []byte) error {
+	type alias KnowledgeBase
+	return unmarshalWithExtra(data, (*alias)(k), &k.Extra)
+}
+
+func (k KnowledgeBase) MarshalJSON() ([]byte, error) {
+	type alias KnowledgeBase
+	return marshalWithExtra(alias(k), k.Extra)
+}
+
+func (t *ToolFunction) UnmarshalJSON(data []byte) error {
+	type alias ToolFunction
+	return unmarshalWithExtra(data, (*alias)(t), &t.Extra)
+}
+
+func (t ToolFunction) MarshalJSON() ([]byte, error) {
+	type alias ToolFunction
+	return marshalWithExtra(alias(t), t.Extra)
+}
+
+func (c *ChunkPlan) UnmarshalJSON(data []byte) error {
+	type alias ChunkPlan
+	return unmarshalWithExtra(data, (*alias)(c), &c.Extra)
+}
+
+func (c ChunkPlan) MarshalJSON() ([]byte, error) {
+	type alias ChunkPlan
+	return marshalWithExtra(alias(c), c.Extra)
+}
+
+func (f *FallbackPlan) UnmarshalJSON(data []byte) error {
+	type alias FallbackPlan
+	return unmarshalWithExtra(data, (*alias)(f), &f.Extra)
+}
+
+func (f FallbackPlan) MarshalJSON() ([]byte, error) {
+	type alias FallbackPlan
+	return marshalWithExtra(alias(f), f.Extra)
+}
+
+func (f *FallbackVoice) UnmarshalJSON(data []byte) error {
+	type alias FallbackVoice
+	return unmarshalWithExtra(data, (*alias)(f), &f.Extra)
+}
+
+func (f FallbackVoice) MarshalJSON() ([]byte, error) {
+	type alias FallbackVoice
+	return marshalWithExtra(alias(f), f.Extra)
+}
+
+func (t *TransportConfig) UnmarshalJSON(data []byte) error {
+	type alias TransportConfig
+	return unmarshalWithExtra(data, (*alias)(t), &t.Extra)
+}
+
+func (t TransportConfig) MarshalJSON() ([]byte, error) {
+	type alias TransportConfig
+	return marshalWithExtra(alias(t), t.Extra)
+}
+
+func (m *MessagePlan) UnmarshalJSON(data []byte) error {
+	type alias MessagePlan
+	return unmarshalWithExtra(data, (*alias)(m), &m.Extra)
+}
+
+func (m MessagePlan) MarshalJSON() ([]byte, error) {
+	type alias MessagePlan
+	return marshalWithExtra(alias(m), m.Extra)
+}
+
+func (c *CustomEndpointingRule) UnmarshalJSON(data []byte) error {
+	type alias CustomEndpointingRule
+	return unmarshalWithExtra(data, (*alias)(c), &c.Extra)
+}
+
+func (c CustomEndpointingRule) MarshalJSON() ([]byte, error) {
+	type alias CustomEndpointingRule
+	return marshalWithExtra(alias(c), c.Extra)
+}
+
+func (m *MonitorPlan) UnmarshalJSON(data []byte) error {
+	type alias MonitorPlan
+	return unmarshalWithExtra(data, (*alias)(m), &m.Extra)
+}
+
+func (m MonitorPlan) MarshalJSON() ([]byte, error) {
+	type alias MonitorPlan
+	return marshalWithExtra(alias(m), m.Extra)
+}
+
+func (c *Cost) UnmarshalJSON(data []byte) error {
+	type alias Cost
+	return unmarshalWithExtra(data, (*alias)(c), &c.Extra)
+}
+
+func (c Cost) MarshalJSON() ([]byte, error) {
+	type alias Cost
+	return marshalWithExtra(alias(c), c.Extra)
+}
+
+func (c *CostBreakdown) UnmarshalJSON(data []byte) error {
+	type alias CostBreakdown
+	return unmarshalWithExtra(data, (*alias)(c), &c.Extra)
+}
+
+func (c CostBreakdown) MarshalJSON() ([]byte, error) {
+	type alias CostBreakdown
+	return marshalWithExtra(alias(c), c.Extra)
+}
+
+func (a *AnalysisCostBreakdown) UnmarshalJSON(data []byte) error {
+	type alias AnalysisCostBreakdown
+	return unmarshalWithExtra(data, (*alias)(a), &a.Extra)
+}
+
+func (a AnalysisCostBreakdown) MarshalJSON() ([]byte, error) {
+	type alias AnalysisCostBreakdown
+	return marshalWithExtra(alias(a), a.Extra)
+}
+
+func (m *Monitor) UnmarshalJSON(data []byte) error {
+	type alias Monitor
+	return unmarshalWithExtra(data, (*alias)(m), &m.Extra)
+}
+
+func (m Monitor) MarshalJSON() ([]byte, error) {
+	type alias Monitor
+	return marshalWithExtra(alias(m), m.Extra)
+}
+
+func (t *Transport) UnmarshalJSON(data []byte) error {
+	type alias Transport
+	return unmarshalWithExtra(data, (*alias)(t), &t.Extra)
+}
+
+func (t Transport) MarshalJSON() ([]byte, error) {
+	type alias Transport
+	return marshalWithExtra(alias(t), t.Extra)
+}
+
+func (d *Destination) UnmarshalJSON(data []byte) error {
+	type alias Destination
+	return unmarshalWithExtra(data, (*alias)(d), &d.Extra)
+}
+
+func (d Destination) MarshalJSON() ([]byte, error) {
+	type alias Destination
+	return marshalWithExtra(alias(d), d.Extra)
+}
+
+func (t *TransferPlan) UnmarshalJSON(data []byte) error {
+	type alias TransferPlan
+	return unmarshalWithExtra(data, (*alias)(t), &t.Extra)
+}
+
+func (t TransferPlan) MarshalJSON() ([]byte, error) {
+	type alias TransferPlan
+	return marshalWithExtra(alias(t), t.Extra)
+}
+
+func (a *ArtifactPlan) UnmarshalJSON(data []byte) error {
+	type alias ArtifactPlan
+	return unmarshalWithExtra(data, (*alias)(a), &a.Extra)
+}
+
+func (a ArtifactPlan) MarshalJSON() ([]byte, error) {
+	type alias ArtifactPlan
+	return marshalWithExtra(alias(a), a.Extra)
+}
+
+func (t *TranscriptPlan) UnmarshalJSON(data []byte) error {
+	type alias TranscriptPlan
+	return unmarshalWithExtra(data, (*alias)(t), &t.Extra)
+}
+
+func (t TranscriptPlan) MarshalJSON() ([]byte, error) {
+	type alias TranscriptPlan
+	return marshalWithExtra(alias(t), t.Extra)
+}
+
+func (t *ToolCall) UnmarshalJSON(data []byte) error {
+	type alias ToolCall
+	return unmarshalWithExtra(data, (*alias)(t), &t.Extra)
+}
+
+func (t ToolCall) MarshalJSON() ([]byte, error) {
+	type alias ToolCall
+	return marshalWithExtra(alias(t), t.Extra)
+}
+
+func (o *OpenAIMessage) UnmarshalJSON(data []byte) error {
+	type alias OpenAIMessage
+	return unmarshalWithExtra(data, (*alias)(o), &o.Extra)
+}
+
+func (o OpenAIMessage) MarshalJSON() ([]byte, error) {
+	type alias OpenAIMessage
+	return marshalWithExtra(alias(o), o.Extra)
+}
+
+func (a *AssistantRequest) UnmarshalJSON(data []byte) error {
+	type alias AssistantRequest
+	return unmarshalWithExtra(data, (*alias)(a), &a.Extra)
+}
+
+func (a AssistantRequest) MarshalJSON() ([]byte, error) {
+	type alias AssistantRequest
+	return marshalWithExtra(alias(a), a.Extra)
+}
+
+func (a *AssistantRequestResponse) UnmarshalJSON(data []byte) error {
+	type alias AssistantRequestResponse
+	return unmarshalWithExtra(data, (*alias)(a), &a.Extra)
+}
+
+func (a AssistantRequestResponse) MarshalJSON() ([]byte, error) {
+	type alias AssistantRequestResponse
+	return marshalWithExtra(alias(a), a.Extra)
+}
+
+func (e *EndOfCallReport) UnmarshalJSON(data []byte) error {
+	type alias EndOfCallReport
+	return unmarshalWithExtra(data, (*alias)(e), &e.Extra)
+}
+
+func (e EndOfCallReport) MarshalJSON() ([]byte, error) {
+	type alias EndOfCallReport
+	return marshalWithExtra(alias(e), e.Extra)
+}
+
+func (c *ConversationUpdate) UnmarshalJSON(data []byte) error {
+	type alias ConversationUpdate
+	return unmarshalWithExtra(data, (*alias)(c), &c.Extra)
+}
+
+func (c ConversationUpdate) MarshalJSON() ([]byte, error) {
+	type alias ConversationUpdate
+	return marshalWithExtra(alias(c), c.Extra)
+}
+
+// UnmarshalJSON accepts arguments both as the JSON-encoded string OpenAI
+// uses and as a plain object, which some Vapi messages carry.
+func (f *ToolCallFunction) UnmarshalJSON(data []byte) error {
+	var raw struct {
+		Name      string          `json:"name"`
+		Arguments json.RawMessage `json:"arguments"`
+	}
+	if err := unmarshalWithExtra(data, &raw, &f.Extra); err != nil {
+		return err
+	}
+	f.Name = raw.Name
+	f.Arguments = ""
+	f.rawArguments = nil
+	if len(raw.Arguments) == 0 || string(raw.Arguments) == "null" {
+		return nil
+	}
+	if raw.Arguments[0] == '"' {
+		return json.Unmarshal(raw.Arguments, &f.Arguments)
+	}
+	f.Arguments = string(raw.Arguments)
+	f.rawArguments = raw.Arguments
+	return nil
+}
+
+// MarshalJSON writes arguments in the form they were decoded from: object
+// arguments stay an object unless Arguments has been changed since.
+func (f ToolCallFunction) MarshalJSON() ([]byte, error) {
+	type alias ToolCallFunction
+	if f.rawArguments == nil || f.Arguments != string(f.rawArguments) {
+		return marshalWithExtra(alias(f), f.Extra)
+	}
+	return marshalWithExtra(struct {
+		alias
+		Arguments json.RawMessage `json:"arguments"`
+	}{alias(f), f.rawArguments}, f.Extra)
+}
@@ -0,0 +1,85 @@
+package vapi
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+const testAssistantJSON = `{
+	"id": "asst-1",
+	"name": "Alex",
+	"hipaaEnabled": true,
+	"model": {"provider": "openai", "model": "gpt-4o", "knowledgeBaseId": "kb-1", "toolCallsPlan": {"mode": "parallel"}},
+	"voice": {"provider": "11labs", "voiceId": "v", "model": "eleven_flash_v2_5", "speed": 1.1},
+	"analysisPlan": {"summaryPlan": {"messages": [{"role": "system", "content": "Summarize.", "name": "s"}], "enabled": true}}
+}`
+
+func TestAssistant_RoundTripKeepsUnknownFields(t *testing.T) {
+	var a Assistant
+	if err := json.Unmarshal([]byte(testAssistantJSON), &a); err != nil {
+		t.Fatalf("Unmarshal() error = %v", err)
+	}
+
+	if string(a.Extra["hipaaEnabled"]) != "true" {
+		t.Errorf("Extra[hipaaEnabled] = %s, want true", a.Extra["hipaaEnabled"])
+	}
+	if _, ok := a.Model.Extra["knowledgeBaseId"]; ok {
+		t.Error("known field knowledgeBaseId ended up in Extra")
+	}
+
+	b, err := json.Marshal(a)
+	if err != nil {
+		t.Fatalf("Marshal() error = %v", err)
+	}
+
+	var want, got map[string]any
+	json.Unmarshal([]byte(testAssistantJSON), &want)
+	json.Unmarshal(b, &got)
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("round trip mismatch\n got: %v\nwant: %v", got, want)
+	}
+}
+
+type testEmbeddedBase struct {
+	ID string `json:"id"`
+}
+
+type testEmbedding struct {
+	testEmbeddedBase
+	Name string `json:"name"`
+
+	Extra map[string]json.RawMessage `json:"-"`
+}
+
+func TestUnmarshalWithExtra_EmbeddedFields(t *testing.T) {
+	type alias testEmbedding
+	var v testEmbedding
+	if err := unmarshalWithExtra([]byte(`{"id": "e-1", "name": "n", "other": 1}`), (*alias)(&v), &v.Extra); err != nil {
+		t.Fatalf("unmarshalWithExtra() error = %v", err)
+	}
+	if v.ID != "e-1" {
+		t.Errorf("embedded ID = %q, want e-1", v.ID)
+	}
+	if got := UnknownFields(v); !reflect.DeepEqual(got, []string{"other"}) {
+		t.Errorf("UnknownFields() = %v, want [other]", got)
+	}
+}
+
+func TestDecodeStrict(t *testing.T) {
+	var a Assistant
+	err := DecodeStrict([]byte(testAssistantJSON), &a)
+
+	var unknown *UnknownFieldsError
+	if !errors.As(err, &unknown) {
+		t.Fatalf("DecodeStrict() error = %v, want *UnknownFieldsError", err)
+	}
+	want := []string{"analysisPlan.summaryPlan.enabled", "analysisPlan.summaryPlan.messages[0].name", "hipaaEnabled", "model.toolCallsPlan", "voice.speed"}
+	if !reflect.DeepEqual(unknown.Fields, want) {
+		t.Errorf("Fields = %v, want %v", unknown.Fields, want)
+	}
+	if a.Name == nil || *a.Name != "Alex" {
+		t.Error("DecodeStrict() did not populate the value")
+	}
+}
@@ -0,0 +1,136 @@
+package vapi
+
+import (
+	"fmt"
+	"strings"
+)
+
+// DefaultLocale is the last locale tried before prompts without a locale
+var DefaultLocale = "en"
+
+// NormalizeLocale returns locale in canonical BCP 47 case: "es_mx" and
+// "ES-mx" become "es-MX", "zh-hant-tw" becomes "zh-Hant-TW".
+func NormalizeLocale(locale string) string {
+	parts := strings.FieldsFunc(locale, func(r rune) bool { return r == '-' || r == '_' })
+	for i, p := range parts {
+		switch {
+		case i == 0:
+			parts[i] = strings.ToLower(p)
+		case len(p) == 2:
+			parts[i] = strings.ToUpper(p)
+		case len(p) == 4:
+			parts[i] = strings.ToUpper(p[:1]) + strings.ToLower(p[1:])
+		default:
+			parts[i] = strings.ToLower(p)
+		}
+	}
+	return strings.Join(parts, "-")
+}
+
+// LocaleFallbacks returns the locales to try for locale, most specific
+// first: the locale itself, each shorter prefix of it, then DefaultLocale
+// and finally "" for prompts and profiles without a locale. "es-MX" gives
+// es-MX, es, en, "".
+func LocaleFallbacks(locale string) []string {
+	var chain []string
+	add := func(locale string) {
+		for locale != "" {
+			if !contains(chain, locale) {
+				chain = append(chain, locale)
+			}
+			i := strings.LastIndexByte(locale, '-')
+			if i < 0 {
+				break
+			}
+			locale = locale[:i]
+		}
+	}
+	add(NormalizeLocale(locale))
+	add(NormalizeLocale(DefaultLocale))
+	return append(chain, "")
+}
+
+// LocaleProfile is the voice and transcriber an assistant uses for a
+// locale. Nil fields fall back to the next locale in LocaleFallbacks.
+type LocaleProfile struct {
+	Transcriber *TranscriberConfig
+	Voice       *ElevenLabsVoiceConfig
+}
+
+// LocaleProfiles maps locales to profiles:
+//
+//	profiles := vapi.LocaleProfiles{
+//		"en":    {Voice: &englishVoice},
+//		"es":    {Voice: &spanishVoice, Transcriber: &vapi.TranscriberConfig{Provider: "deepgram", Model: "nova-2", Language: "es"}},
+//		"es-MX": {Voice: &mexicanVoice},
+//	}
+type LocaleProfiles map[string]LocaleProfile
+
+// Lookup returns the profile for locale, resolving each field separately
+// along LocaleFallbacks. With the profiles above, "es-MX" gets the Mexican
+// voice and the Spanish transcriber.
+func (p LocaleProfiles) Lookup(locale string) LocaleProfile {
+	byLocale := make(map[string]LocaleProfile, len(p))
+	for loc, profile := range p {
+		byLocale[NormalizeLocale(loc)] = profile
+	}
+
+	var result LocaleProfile
+	for _, loc := range LocaleFallbacks(locale) {
+		profile, ok := byLocale[loc]
+		if !ok {
+			continue
+		}
+		if result.Transcriber == nil {
+			result.Transcriber = profile.Transcriber
+		}
+		if result.Voice == nil {
+			result.Voice = profile.Voice
+		}
+	}
+	return result
+}
+
+// WithLocale sets the voice and transcriber for locale from profiles. When
+// no profile has a transcriber, the current transcriber is kept with its
+// language set to the locale's language. The locale is also used by
+// WithLibraryPrompt.
+func (b *AssistantBuilder) WithLocale(locale string, profiles LocaleProfiles) *AssistantBuilder {
+	b.locale = NormalizeLocale(locale)
+	profile := profiles.Lookup(b.locale)
+
+	if profile.Transcriber != nil {
+		transcriber := *profile.Transcriber
+		b.assistant.Transcriber = &transcriber
+	} else if b.locale != "" {
+		transcriber := DefaultTranscriber
+		if b.assistant.Transcriber != nil {
+			transcriber = *b.assistant.Transcriber
+		}
+		transcriber.Language = strings.SplitN(b.locale, "-", 2)[0]
+		b.assistant.Transcriber = &transcriber
+	}
+	if profile.Voice != nil {
+		voice := *profile.Voice
+		b.assistant.Voice = &voice
+	}
+	return b
+}
+
+// WithLibraryPrompt renders the latest version of the named prompt into the
+// assistant with Prompt.ApplyToAssistant, picking the closest locale to the
+// one set with WithLocale. Errors are returned by Build.
+func (b *AssistantBuilder) WithLibraryPrompt(lib *PromptLibrary, name string, data any) *AssistantBuilder {
+	if b.err != nil {
+		return b
+	}
+	prompt, err := lib.LatestLocale(name, b.locale)
+	if err != nil {
+		b.err = err
+		return b
+	}
+	if err := prompt.ApplyToAssistant(&b.assistant, data); err != nil {
+		b.err = fmt.Errorf("prompt %q: %w", name, err)
+	}
+	return b
+}
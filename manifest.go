@@ -0,0 +1,369 @@
+package vapi
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+
+	"gopkg.in/yaml.v2"
+)
+
+// Manifest declares the assistants, tools and squads that should exist in a
+// Vapi org. It is usually kept in git and loaded with LoadManifest:
+//
+//	assistants:
+//	  - id: 0f7534fa-deee-4feb-a48f-6e6e64eb38e7
+//	    spec:
+//	      name: Alex
+//	      firstMessage: Hi, this is Alex.
+//	tools:
+//	  - spec:
+//	      type: function
+//	      function:
+//	        name: lookupOrder
+//
+// The spec of each resource uses the same field names as the Vapi JSON API.
+type Manifest struct {
+	Assistants []ManifestResource `yaml:"assistants,omitempty"`
+	Tools      []ManifestResource `yaml:"tools,omitempty"`
+	Squads     []ManifestResource `yaml:"squads,omitempty"`
+}
+
+// ManifestResource is a single resource in a Manifest. Resources without an
+// ID are created; resources with an ID are updated in place, or deleted when
+// Delete is set.
+type ManifestResource struct {
+	ID     string         `yaml:"id,omitempty"`
+	Delete bool           `yaml:"delete,omitempty"`
+	Spec   map[string]any `yaml:"spec,omitempty"`
+}
+
+// Resource kinds that can appear in a manifest
+const (
+	ManifestKindAssistant = "assistant"
+	ManifestKindTool      = "tool"
+	ManifestKindSquad     = "squad"
+)
+
+// PlanAction is what applying a PlanItem will do to the remote resource
+type PlanAction string
+
+const (
+	PlanCreate PlanAction = "create"
+	PlanUpdate PlanAction = "update"
+	PlanDelete PlanAction = "delete"
+	PlanNoOp   PlanAction = "no-op"
+)
+
+// PlanItem is the planned change for one manifest resource
+type PlanItem struct {
+	Kind    string
+	Name    string
+	ID      string
+	Action  PlanAction
+	Changes []FieldChange
+
+	// spec is the resource as written in the manifest. It is sent as is so
+	// fields the user left out are not sent as zero values.
+	spec map[string]any
+}
+
+// Plan is the set of changes needed to bring the remote org in line with a
+// Manifest.
+type Plan struct {
+	Items []PlanItem
+}
+
+// LoadManifest reads a YAML manifest from disk
+func LoadManifest(path string) (*Manifest, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	m, err := ParseManifest(content)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse manifest %s: %w", path, err)
+	}
+	return m, nil
+}
+
+// ParseManifest parses a YAML (or JSON) manifest
+func ParseManifest(content []byte) (*Manifest, error) {
+	var m Manifest
+	if err := yaml.UnmarshalStrict(content, &m); err != nil {
+		return nil, err
+	}
+
+	for _, list := range [][]ManifestResource{m.Assistants, m.Tools, m.Squads} {
+		for i := range list {
+			list[i].Spec = normalizeYAML(list[i].Spec).(map[string]any)
+		}
+	}
+	return &m, nil
+}
+
+// normalizeYAML converts the map[interface{}]interface{} values produced by
+// yaml.v2 into map[string]any so they can be marshalled as JSON.
+func normalizeYAML(v any) any {
+	switch t := v.(type) {
+	case map[any]any:
+		m := make(map[string]any, len(t))
+		for k, val := range t {
+			m[fmt.Sprint(k)] = normalizeYAML(val)
+		}
+		return m
+	case map[string]any:
+		m := make(map[string]any, len(t))
+		for k, val := range t {
+			m[k] = normalizeYAML(val)
+		}
+		return m
+	case []any:
+		for i := range t {
+			t[i] = normalizeYAML(t[i])
+		}
+		return t
+	case nil:
+		return map[string]any{}
+	}
+	return v
+}
+
+// decodeSpec decodes a manifest spec into the typed resource for kind to
+// validate it. Unknown fields are rejected so typos show up before anything
+// is sent.
+func decodeSpec(kind string, spec map[string]any) (any, error) {
+	var out any
+	switch kind {
+	case ManifestKindAssistant:
+		out = &Assistant{}
+	case ManifestKindTool:
+		out = &Tool{}
+	case ManifestKindSquad:
+		out = &Squad{}
+	default:
+		return nil, fmt.Errorf("unknown manifest kind %q", kind)
+	}
+
+	b, err := json.Marshal(spec)
+	if err != nil {
+		return nil, err
+	}
+	if err := DecodeStrict(b, out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// getRemote fetches the current server copy of a resource
+func getRemote(ctx context.Context, kind, id string) (any, error) {
+	switch kind {
+	case ManifestKindAssistant:
+		a, _, err := getAssistant(ctx, id)
+		return a, err
+	case ManifestKindTool:
+		return GetTool(ctx, id)
+	case ManifestKindSquad:
+		return GetSquad(ctx, id)
+	}
+	return nil, fmt.Errorf("unknown manifest kind %q", kind)
+}
+
+// PlanManifest compares every resource in the manifest with what the Vapi API
+// currently returns for it and produces a field-level plan. Only fields set
+// in the manifest are compared, so server defaults do not show up as changes.
+func PlanManifest(ctx context.Context, m *Manifest) (*Plan, error) {
+	var plan Plan
+
+	kinds := []struct {
+		kind      string
+		resources []ManifestResource
+	}{
+		{ManifestKindTool, m.Tools},
+		{ManifestKindAssistant, m.Assistants},
+		{ManifestKindSquad, m.Squads},
+	}
+
+	for _, k := range kinds {
+		for i, res := range k.resources {
+			item := PlanItem{Kind: k.kind, ID: res.ID}
+			if name, ok := res.Spec["name"].(string); ok {
+				item.Name = name
+			} else if fn, ok := res.Spec["function"].(map[string]any); ok {
+				item.Name, _ = fn["name"].(string)
+			}
+
+			if res.Delete {
+				if res.ID == "" {
+					return nil, fmt.Errorf("%s %d: delete requires an id", k.kind, i)
+				}
+				item.Action = PlanDelete
+				plan.Items = append(plan.Items, item)
+				continue
+			}
+
+			if _, err := decodeSpec(k.kind, res.Spec); err != nil {
+				return nil, fmt.Errorf("%s %d: invalid spec: %w", k.kind, i, err)
+			}
+			item.spec = res.Spec
+
+			newValue, err := toJSONValue(res.Spec)
+			if err != nil {
+				return nil, err
+			}
+
+			if res.ID == "" {
+				item.Action = PlanCreate
+				diffJSONValues("", nil, newValue, true, &item.Changes)
+				plan.Items = append(plan.Items, item)
+				continue
+			}
+
+			remote, err := getRemote(ctx, k.kind, res.ID)
+			if err != nil {
+				return nil, fmt.Errorf("%s %s: %w", k.kind, res.ID, err)
+			}
+			oldValue, err := toJSONValue(remote)
+			if err != nil {
+				return nil, err
+			}
+
+			diffJSONValues("", oldValue, newValue, true, &item.Changes)
+			item.Action = PlanNoOp
+			if len(item.Changes) > 0 {
+				item.Action = PlanUpdate
+			}
+			plan.Items = append(plan.Items, item)
+		}
+	}
+
+	return &plan, nil
+}
+
+// Write prints the plan as a human readable diff
+func (p *Plan) Write(w io.Writer) error {
+	symbols := map[PlanAction]string{
+		PlanCreate: "+",
+		PlanUpdate: "~",
+		PlanDelete: "-",
+		PlanNoOp:   "=",
+	}
+
+	for _, item := range p.Items {
+		label := item.Name
+		if label == "" {
+			label = item.ID
+		}
+		if _, err := fmt.Fprintf(w, "%s %s %q (%s)\n", symbols[item.Action], item.Kind, label, item.Action); err != nil {
+			return err
+		}
+		if item.Action == PlanCreate || item.Action == PlanDelete {
+			continue
+		}
+		for _, c := range item.Changes {
+			if _, err := fmt.Fprintf(w, "    %s\n", c); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// ApplyPlan prints the plan to w and then executes it against the Vapi API.
+// With dryRun set the plan is only printed. Items are applied in
+// order (tools, then assistants, then squads) and created resources have their
+// ID filled in on the plan.
+func ApplyPlan(ctx context.Context, p *Plan, dryRun bool, w io.Writer) error {
+	if err := p.Write(w); err != nil {
+		return err
+	}
+	if dryRun {
+		return nil
+	}
+
+	for i := range p.Items {
+		item := &p.Items[i]
+		if err := applyPlanItem(ctx, item); err != nil {
+			return fmt.Errorf("failed to %s %s %q: %w", item.Action, item.Kind, item.Name, err)
+		}
+	}
+	return nil
+}
+
+func applyPlanItem(ctx context.Context, item *PlanItem) error {
+	switch item.Action {
+	case PlanNoOp:
+		return nil
+	case PlanDelete:
+		switch item.Kind {
+		case ManifestKindAssistant:
+			return DeleteAssistant(ctx, item.ID)
+		case ManifestKindTool:
+			return DeleteTool(ctx, item.ID)
+		case ManifestKindSquad:
+			return DeleteSquad(ctx, item.ID)
+		}
+		return fmt.Errorf("unknown manifest kind %q", item.Kind)
+	case PlanCreate, PlanUpdate:
+	default:
+		return fmt.Errorf("unknown plan action %q", item.Action)
+	}
+
+	// The spec is sent as written rather than as the typed resource, so
+	// fields the manifest leaves out are not sent as zero values. The ID is
+	// in the path of an update; the API rejects it in the body.
+	payload := make(map[string]any, len(item.spec))
+	for k, v := range item.spec {
+		if k != "id" {
+			payload[k] = v
+		}
+	}
+	create := item.Action == PlanCreate
+
+	var id *string
+	var err error
+	switch item.Kind {
+	case ManifestKindAssistant:
+		var a *Assistant
+		if create {
+			a, err = createAssistant(ctx, payload)
+		} else {
+			a, err = updateAssistant(ctx, item.ID, payload)
+		}
+		if a != nil {
+			id = a.ID
+		}
+	case ManifestKindTool:
+		var t *Tool
+		if create {
+			t, err = createTool(ctx, payload)
+		} else {
+			t, err = updateTool(ctx, item.ID, payload)
+		}
+		if t != nil {
+			id = t.ID
+		}
+	case ManifestKindSquad:
+		var s *Squad
+		if create {
+			s, err = createSquad(ctx, payload)
+		} else {
+			s, err = updateSquad(ctx, item.ID, payload)
+		}
+		if s != nil {
+			id = s.ID
+		}
+	default:
+		return fmt.Errorf("unknown manifest kind %q", item.Kind)
+	}
+	if err != nil {
+		return err
+	}
+	if id != nil {
+		item.ID = *id
+	}
+	return nil
+}
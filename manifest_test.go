@@ -0,0 +1,144 @@
+package vapi
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+const testManifest = `
+tools:
+  - id: tool-1
+    spec:
+      type: function
+      async: false
+      function:
+        name: lookupOrder
+        description: Look up an order by number
+assistants:
+  - spec:
+      name: Alex
+      firstMessage: Hi, this is Alex.
+squads:
+  - id: squad-1
+    delete: true
+`
+
+func TestPlanManifest(t *testing.T) {
+	var requests []string
+	bodies := map[string]string{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests = append(requests, r.Method+" "+r.URL.Path)
+		body, _ := io.ReadAll(r.Body)
+		bodies[r.Method+" "+r.URL.Path] = string(body)
+		switch r.Method + " " + r.URL.Path {
+		case "GET /tool/tool-1":
+			json.NewEncoder(w).Encode(map[string]any{
+				"id":    "tool-1",
+				"orgId": "org-1",
+				"type":  "function",
+				"async": false,
+				"function": map[string]any{
+					"name":        "lookupOrder",
+					"description": "Find an order",
+				},
+			})
+		case "PATCH /tool/tool-1":
+			json.NewEncoder(w).Encode(map[string]any{"id": "tool-1", "type": "function"})
+		case "POST /assistant":
+			w.WriteHeader(http.StatusCreated)
+			json.NewEncoder(w).Encode(map[string]any{"id": "asst-1", "name": "Alex"})
+		case "DELETE /squad/squad-1":
+			w.WriteHeader(http.StatusOK)
+		default:
+			http.Error(w, "unexpected request", http.StatusNotFound)
+		}
+	}))
+	defer srv.Close()
+
+	oldBase := apiBaseURL
+	apiBaseURL = srv.URL
+	defer func() { apiBaseURL = oldBase }()
+	t.Setenv("VAPI_API_KEY", "test")
+
+	m, err := ParseManifest([]byte(testManifest))
+	if err != nil {
+		t.Fatalf("ParseManifest() error = %v", err)
+	}
+
+	ctx := context.Background()
+	plan, err := PlanManifest(ctx, m)
+	if err != nil {
+		t.Fatalf("PlanManifest() error = %v", err)
+	}
+
+	wantActions := []PlanAction{PlanUpdate, PlanCreate, PlanDelete}
+	if len(plan.Items) != len(wantActions) {
+		t.Fatalf("got %d plan items, want %d", len(plan.Items), len(wantActions))
+	}
+	for i, want := range wantActions {
+		if plan.Items[i].Action != want {
+			t.Errorf("item %d action = %s, want %s", i, plan.Items[i].Action, want)
+		}
+	}
+
+	changes := plan.Items[0].Changes
+	if len(changes) != 1 || changes[0].Path != "function.description" {
+		t.Fatalf("tool changes = %v, want only function.description", changes)
+	}
+
+	var out bytes.Buffer
+	requests = nil
+	if err := ApplyPlan(ctx, plan, true, &out); err != nil {
+		t.Fatalf("ApplyPlan(dryRun) error = %v", err)
+	}
+	if len(requests) != 0 {
+		t.Errorf("dry run sent requests: %v", requests)
+	}
+	if !strings.Contains(out.String(), `function.description: "Find an order" -> "Look up an order by number"`) {
+		t.Errorf("dry run output missing diff:\n%s", out.String())
+	}
+
+	if err := ApplyPlan(ctx, plan, false, &out); err != nil {
+		t.Fatalf("ApplyPlan() error = %v", err)
+	}
+	want := []string{"PATCH /tool/tool-1", "POST /assistant", "DELETE /squad/squad-1"}
+	if strings.Join(requests, ",") != strings.Join(want, ",") {
+		t.Errorf("requests = %v, want %v", requests, want)
+	}
+	// Only the fields written in the manifest are sent
+	for req, want := range map[string]string{
+		"PATCH /tool/tool-1": `{"async":false,"function":{"description":"Look up an order by number","name":"lookupOrder"},"type":"function"}`,
+		"POST /assistant":    `{"firstMessage":"Hi, this is Alex.","name":"Alex"}`,
+	} {
+		if bodies[req] != want {
+			t.Errorf("%s body = %s, want %s", req, bodies[req], want)
+		}
+	}
+	if plan.Items[1].ID != "asst-1" {
+		t.Errorf("created assistant ID = %q, want asst-1", plan.Items[1].ID)
+	}
+}
+
+func TestParseManifest_UnknownField(t *testing.T) {
+	m, err := ParseManifest([]byte("assistants:\n  - spec:\n      firstMesage: typo\n"))
+	if err != nil {
+		t.Fatalf("ParseManifest() error = %v", err)
+	}
+	if _, err := PlanManifest(context.Background(), m); err == nil {
+		t.Error("PlanManifest() expected error for unknown spec field")
+	}
+
+	m, err = ParseManifest([]byte("assistants:\n  - spec:\n      analysisPlan:\n        summaryPlan:\n          timeoutSecs: 10\n"))
+	if err != nil {
+		t.Fatalf("ParseManifest() error = %v", err)
+	}
+	if _, err := PlanManifest(context.Background(), m); err == nil || !strings.Contains(err.Error(), "analysisPlan.summaryPlan.timeoutSecs") {
+		t.Errorf("PlanManifest() error = %v, want nested unknown field", err)
+	}
+}
@@ -0,0 +1,192 @@
+package vapi
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// vapiVariablePattern matches Vapi's Liquid-style {{variable}} placeholders,
+// including dotted paths and filters: {{ customer.name | upcase }}.
+var vapiVariablePattern = regexp.MustCompile(`\{\{\s*([A-Za-z_][\w.]*)\s*(\|[^}]*)?\}\}`)
+
+// MergeAssistant applies overrides to base the way Vapi does when a call is
+// started with assistantOverrides: nested objects are merged field by field,
+// while scalars and lists in overrides replace the base value. VariableValues
+// are merged and then substituted into the first message, end-call and
+// voicemail messages and the model messages, so the result is the exact
+// configuration the call runs with.
+//
+// Fields unset in overrides keep the base value. A field is unset when it
+// is a nil pointer, or a zero value in a field without omitempty, since
+// those are always sent and would otherwise blank the base value. Pointer
+// and omitempty fields can be set to their zero value explicitly, e.g. a
+// *bool override of false turns a setting off.
+func MergeAssistant(base, overrides *Assistant) (*Assistant, error) {
+	merged := map[string]any{}
+	for i, a := range []*Assistant{base, overrides} {
+		if a == nil {
+			continue
+		}
+		v, err := toJSONValue(a)
+		if err != nil {
+			return nil, err
+		}
+		m, ok := v.(map[string]any)
+		if !ok {
+			continue
+		}
+		if i == 1 {
+			pruneUnsetFields(reflect.ValueOf(a), m)
+		}
+		merged = mergeJSONMaps(merged, m)
+	}
+
+	b, err := json.Marshal(merged)
+	if err != nil {
+		return nil, err
+	}
+	var result Assistant
+	if err := json.Unmarshal(b, &result); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal merged assistant: %w", err)
+	}
+
+	if len(result.VariableValues) > 0 {
+		substitute := func(s *string) {
+			if s != nil {
+				*s = SubstituteVariables(*s, result.VariableValues)
+			}
+		}
+		substitute(result.FirstMessage)
+		substitute(result.EndCallMessage)
+		substitute(result.VoicemailMessage)
+		if result.Model != nil {
+			for i := range result.Model.Messages {
+				substitute(&result.Model.Messages[i].Content)
+			}
+		}
+	}
+
+	return &result, nil
+}
+
+// mergeJSONMaps merges src into dst, recursing into nested objects
+func mergeJSONMaps(dst, src map[string]any) map[string]any {
+	for k, v := range src {
+		if sm, ok := v.(map[string]any); ok {
+			if dm, ok := dst[k].(map[string]any); ok {
+				dst[k] = mergeJSONMaps(dm, sm)
+				continue
+			}
+		}
+		dst[k] = v
+	}
+	return dst
+}
+
+// pruneUnsetFields removes the fields of v that are unset from m, its JSON
+// encoding, recursing into nested structs. Whether a field is set depends
+// on its type and tag rather than its JSON value, so explicit zero values
+// survive.
+func pruneUnsetFields(v reflect.Value, m map[string]any) {
+	for v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return
+		}
+		v = v.Elem()
+	}
+	if v.Kind() != reflect.Struct {
+		return
+	}
+
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if !f.IsExported() {
+			continue
+		}
+		name, opts, _ := strings.Cut(f.Tag.Get("json"), ",")
+		if name == "-" {
+			continue
+		}
+		fv := v.Field(i)
+		if f.Anonymous && name == "" {
+			pruneUnsetFields(fv, m)
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		if _, ok := m[name]; !ok {
+			continue
+		}
+
+		switch fv.Kind() {
+		case reflect.Ptr, reflect.Interface, reflect.Map, reflect.Slice:
+			if fv.IsNil() {
+				delete(m, name)
+				continue
+			}
+		default:
+			if fv.IsZero() && !strings.Contains(","+opts+",", ",omitempty,") {
+				delete(m, name)
+				continue
+			}
+		}
+		if nested, ok := m[name].(map[string]any); ok {
+			pruneUnsetFields(fv, nested)
+		}
+	}
+}
+
+// SubstituteVariables replaces Vapi {{variable}} placeholders in s with
+// values from vars. Dotted names look up nested maps. Placeholders without a
+// value are left untouched, since Vapi fills some (like {{customer.number}})
+// itself at call time. Placeholders with Liquid filters, such as
+// {{name | upcase}}, are left for Vapi to render as well.
+func SubstituteVariables(s string, vars map[string]any) string {
+	return vapiVariablePattern.ReplaceAllStringFunc(s, func(match string) string {
+		m := vapiVariablePattern.FindStringSubmatch(match)
+		if m[2] != "" {
+			return match
+		}
+		v, ok := lookupVariable(vars, m[1])
+		if !ok {
+			return match
+		}
+		return formatVariable(v)
+	})
+}
+
+func lookupVariable(vars map[string]any, name string) (any, bool) {
+	if v, ok := vars[name]; ok {
+		return v, true
+	}
+
+	var cur any = vars
+	for _, part := range strings.Split(name, ".") {
+		m, ok := cur.(map[string]any)
+		if !ok {
+			return nil, false
+		}
+		if cur, ok = m[part]; !ok {
+			return nil, false
+		}
+	}
+	return cur, true
+}
+
+func formatVariable(v any) string {
+	switch t := v.(type) {
+	case string:
+		return t
+	case float64:
+		return strconv.FormatFloat(t, 'f', -1, 64)
+	case nil:
+		return ""
+	}
+	return fmt.Sprint(v)
+}
@@ -1,5 +1,7 @@
 package vapi
 
+import "encoding/json"
+
 // ModelConfig contains LLM settings
 type ModelConfig struct {
 	Provider                  string         `json:"provider,omitempty"`
@@ -13,10 +15,14 @@ type ModelConfig struct {
 	Temperature               float64        `json:"temperature,omitempty"`
 	ToolIDs                   []string       `json:"toolIds,omitempty"`
 	Tools                     []Tool         `json:"tools,omitempty"`
+
+	Extra map[string]json.RawMessage `json:"-"`
 }
 
 // ModelMessage represents a single message in the model conversation
 type ModelMessage struct {
 	Role    string `json:"role"`
 	Content string `json:"content"`
+
+	Extra map[string]json.RawMessage `json:"-"`
 }
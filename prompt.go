@@ -4,9 +4,11 @@ import (
 	"crypto/sha256"
 	"encoding/hex"
 	"fmt"
-	"html/template"
+	htmltemplate "html/template"
 	"os"
+	"path/filepath"
 	"strings"
+	"text/template"
 
 	"gopkg.in/yaml.v2"
 )
@@ -16,100 +18,155 @@ type PromptHeader struct {
 	Version string         `yaml:"version,omitempty"`
 	Format  string         `yaml:"format,omitempty"`
 	SHA256  string         `yaml:"sha256,omitempty"`
+	Escape  string         `yaml:"escape,omitempty"` // "html" to HTML-escape values
+	Locale  string         `yaml:"locale,omitempty"` // BCP 47 tag such as "es-MX"
+	Inputs  []PromptInput  `yaml:"inputs,omitempty"`
 	Extra   map[string]any `yaml:",inline"`
 }
 
+// Prompt is a parsed prompt template. Prompts are rendered as plain text
+// with PromptFuncs available; set "escape: html" in the header to have
+// values HTML-escaped instead.
 type Prompt struct {
 	Header     PromptHeader
 	Template   *template.Template
 	RawContent string
+
+	// html is set instead of rendering Template when the header asks for
+	// HTML escaping
+	html *htmltemplate.Template
+
+	// bodyLine is the line of the file RawContent starts on
+	bodyLine int
+
+	// sections holds the templates the prompt file itself defines, so
+	// partials are never mistaken for sections
+	sections map[string]bool
 }
 
+// Execute renders the prompt. When the header declares inputs, data is
+// checked against them first and missing or mistyped variables are an error.
 func (p Prompt) Execute(data any) (string, error) {
+	if len(p.Header.Inputs) > 0 {
+		if err := p.CheckData(data).Err(); err != nil {
+			return "", fmt.Errorf("prompt %q: %w", p.Header.Name, err)
+		}
+	}
+
 	var result strings.Builder
+	if p.html != nil {
+		if err := p.html.Execute(&result, data); err != nil {
+			return "", err
+		}
+		return result.String(), nil
+	}
 	if err := p.Template.Execute(&result, data); err != nil {
 		return "", err
 	}
 	return result.String(), nil
 }
 
-func validateTemplateContent(content string) error {
-	// Skip validation for code blocks that might contain valid single braces
-	// Extract content outside of code blocks (indicated by ```...```)
-	contentToCheck := content
-	codeBlocks := strings.Split(content, "```")
-
-	// If there are code blocks, we only want to check the non-code parts
-	if len(codeBlocks) > 1 {
-		// Rebuild the content without code blocks
-		contentToCheck = ""
-		for i, block := range codeBlocks {
-			// Even indices are non-code blocks
-			if i%2 == 0 {
-				contentToCheck += block
-			}
-		}
+// CreatePromptTemplate loads a single prompt file. Partials (files whose
+// name starts with "_") are resolved as if the file's directory were the
+// root of a PromptLibrary: the partials next to the file and under its
+// PartialsDir are available through {{template "name" .}}.
+func CreatePromptTemplate(filePath string) (Prompt, error) {
+	content, err := os.ReadFile(filePath)
+	if err != nil {
+		return Prompt{}, err
 	}
 
-	// Check for unmatched single curly braces in non-code content
-	doubleBraceCount := strings.Count(contentToCheck, "{{")
-	doubleCloseBraceCount := strings.Count(contentToCheck, "}}")
+	partials, err := loadPartials(os.DirFS(filepath.Dir(filePath)))
+	if err != nil {
+		return Prompt{}, err
+	}
 
-	// Count total occurrences of { and }
-	totalLeftBrace := strings.Count(contentToCheck, "{")
-	totalRightBrace := strings.Count(contentToCheck, "}")
+	return parsePromptTemplate(string(content), partials)
+}
 
-	// If there are more single braces than double braces, there must be a single brace
-	if totalLeftBrace > doubleBraceCount*2 {
-		return fmt.Errorf("template contains single left curly brace '{', use '{{' instead")
+// parsePromptTemplate parses the contents of a prompt file and checks the
+// SHA256 in its header. partials maps partial names to their template bodies.
+func parsePromptTemplate(content string, partials map[string]string) (Prompt, error) {
+	prompt, sha, err := compilePrompt(content, partials)
+	if err != nil {
+		return prompt, err
 	}
 
-	if totalRightBrace > doubleCloseBraceCount*2 {
-		return fmt.Errorf("template contains single right curly brace '}', use '}}' instead")
+	if prompt.Header.SHA256 != "" && prompt.Header.SHA256 != sha {
+		return prompt, fmt.Errorf("SHA256 mismatch. Bump the version and update the SHA256 with BumpPromptVersion")
+	} else if prompt.Header.SHA256 == "" {
+		prompt.Header.SHA256 = sha
 	}
-
-	return nil
+	return prompt, nil
 }
 
-func CreatePromptTemplate(filepath string) (Prompt, error) {
+// compilePrompt parses a prompt file and returns it along with the hash of
+// its content. The hash in the header is left as written in the file.
+func compilePrompt(content string, partials map[string]string) (Prompt, string, error) {
 	var prompt Prompt
-	content, err := os.ReadFile(filepath)
+	header, body, bodyLine, err := extractFrontMatter(content)
 	if err != nil {
-		return prompt, err
+		return prompt, "", err
 	}
 
-	header, body, err := extractYAMLHeader(string(content))
+	tmpl, err := template.New(header.Name).Funcs(PromptFuncs()).Parse(body)
 	if err != nil {
-		return prompt, err
+		return prompt, "", err
 	}
 
-	// Validate template content for single curly braces
-	if err := validateTemplateContent(body); err != nil {
-		return prompt, err
+	// Single curly braces are almost always a mistyped action
+	for _, t := range tmpl.Templates() {
+		if problems := singleBraces(t.Tree.Root, body); len(problems) > 0 {
+			l := &linter{body: body, line: bodyLine}
+			line, col := l.position(problems[0].offset)
+			return prompt, "", fmt.Errorf("line %d:%d: %s", line, col, problems[0].msg)
+		}
+	}
+	prompt.sections = map[string]bool{}
+	for _, t := range tmpl.Templates() {
+		prompt.sections[t.Name()] = true
+	}
+	for name, partial := range partials {
+		if isPromptSection(name) {
+			return prompt, "", fmt.Errorf("partial %q has the name of a prompt section", name)
+		}
+		if _, err := tmpl.New(name).Parse(partial); err != nil {
+			return prompt, "", fmt.Errorf("partial %q: %w", name, err)
+		}
 	}
+	prompt.Template = tmpl
 
-	sha, err := sHA256Hash([]byte(body))
+	used, err := usedPartials(tmpl, partials)
 	if err != nil {
-		return prompt, err
+		return prompt, "", err
 	}
-	if header.SHA256 != "" && header.SHA256 != sha {
-		return prompt, fmt.Errorf("SHA256 mismatch. Bump the version and update the SHA256")
-	} else if header.SHA256 == "" {
-		header.SHA256 = sha
-		// if err := SavePromptTemplate(filepath, header, body); err != nil {
-		// 	return prompt, fmt.Errorf("failed to save prompt template while updating hash: %w", err)
-		// }
+
+	sha, err := promptContentHash(body, used, partials)
+	if err != nil {
+		return prompt, "", err
 	}
 	prompt.Header = header
 	prompt.RawContent = body
-
-	tmpl, err := template.New(header.Name).Parse(body)
-	if err != nil {
-		return prompt, err
+	prompt.bodyLine = bodyLine
+
+	switch header.Escape {
+	case "":
+	case "html":
+		html, err := htmltemplate.New(header.Name).Funcs(htmltemplate.FuncMap(PromptFuncs())).Parse(body)
+		if err != nil {
+			return prompt, "", err
+		}
+		for name, partial := range partials {
+			if _, err := html.New(name).Parse(partial); err != nil {
+				return prompt, "", fmt.Errorf("partial %q: %w", name, err)
+			}
+		}
+		prompt.html = html
+	default:
+		return prompt, "", fmt.Errorf("unknown escape mode %q", header.Escape)
 	}
-	prompt.Template = tmpl
 
-	return prompt, nil
+	return prompt, sha, nil
 }
 
 func SavePromptTemplate(filePath string, header PromptHeader, body string) error {
@@ -138,26 +195,19 @@ func SavePromptTemplate(filePath string, header PromptHeader, body string) error
 	return nil
 }
 
-func extractYAMLHeader(templateStr string) (PromptHeader, string, error) {
-	const delimiter = "---"
-	var header PromptHeader
-	parts := strings.Split(templateStr, delimiter)
-	if len(parts) < 3 {
-		return header, templateStr, nil
+// extractFrontMatter splits a prompt file into its header and body. The body
+// is trimmed when the file has a header; bodyLine is the file line the body
+// starts on, so template errors can be mapped back to the file.
+func extractFrontMatter(content string) (header PromptHeader, body string, bodyLine int, err error) {
+	fm, err := parseFrontMatter(content)
+	if err != nil {
+		return header, content, 0, err
 	}
-
-	meta := strings.TrimSpace(parts[1])
-	if strings.HasPrefix(meta, "-") {
-		// the user put too many dashes or it was not the right format
-		return header, templateStr, fmt.Errorf("invalid header format")
+	if header, err = fm.decodeHeader(); err != nil {
+		return header, content, 0, err
 	}
-	body := strings.Join(parts[2:], delimiter)
-
-	if err := yaml.Unmarshal([]byte(meta), &header); err != nil {
-		return header, templateStr, err
-	}
-
-	return header, strings.TrimSpace(body), nil
+	body, bodyLine = fm.body()
+	return header, body, bodyLine, nil
 }
 
 func sHA256Hash(data []byte) (string, error) {
@@ -0,0 +1,252 @@
+package vapi
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+	"unicode"
+)
+
+// PromptFuncs returns the functions available to every prompt template:
+//
+//	{{.Name | default "there"}}                  fallback for empty values
+//	{{join ", " .Items}}                         join a list
+//	{{title .Name}}, {{upper .X}}, {{lower .X}}  change case
+//	{{trim .X}}                                  trim surrounding whitespace
+//	{{date "Monday, January 2" "America/Chicago" .When}}
+//	                                             format a time in a timezone
+//	{{phone .Customer.Number}}                   "4 1 5, 5 5 5, 0 1 2 3"
+//	{{numberWords .Count}}                       "forty-two"
+//
+// A new map is returned on every call so callers can add their own.
+func PromptFuncs() template.FuncMap {
+	return template.FuncMap{
+		"default":     defaultValue,
+		"join":        joinList,
+		"title":       titleCase,
+		"upper":       strings.ToUpper,
+		"lower":       strings.ToLower,
+		"trim":        strings.TrimSpace,
+		"date":        formatDate,
+		"phone":       speakPhoneNumber,
+		"numberWords": numberToWords,
+	}
+}
+
+// defaultValue returns def when value is empty. The argument order lets it
+// be used in a pipeline: {{.Name | default "there"}}.
+func defaultValue(def, value any) any {
+	if isEmptyValue(value) {
+		return def
+	}
+	return value
+}
+
+func isEmptyValue(v any) bool {
+	if v == nil {
+		return true
+	}
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		return rv.IsNil() || isEmptyValue(rv.Elem().Interface())
+	case reflect.Slice, reflect.Map, reflect.Array, reflect.String:
+		return rv.Len() == 0
+	}
+	return rv.IsZero()
+}
+
+func joinList(sep string, list any) (string, error) {
+	rv := reflect.ValueOf(list)
+	if rv.Kind() != reflect.Slice && rv.Kind() != reflect.Array {
+		return "", fmt.Errorf("join: expected a list, got %T", list)
+	}
+	parts := make([]string, rv.Len())
+	for i := range parts {
+		parts[i] = fmt.Sprint(rv.Index(i).Interface())
+	}
+	return strings.Join(parts, sep), nil
+}
+
+// titleCase upper-cases the first letter of every word
+func titleCase(s string) string {
+	runes := []rune(s)
+	start := true
+	for i, r := range runes {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) || r == '\'' {
+			if start {
+				runes[i] = unicode.ToTitle(r)
+			}
+			start = false
+		} else {
+			start = true
+		}
+	}
+	return string(runes)
+}
+
+// formatDate formats t (a time.Time, *time.Time, RFC 3339 string or Unix
+// seconds) with the Go layout in the named IANA timezone.
+func formatDate(layout, tz string, t any) (string, error) {
+	var when time.Time
+	switch v := t.(type) {
+	case time.Time:
+		when = v
+	case *time.Time:
+		if v == nil {
+			return "", nil
+		}
+		when = *v
+	case string:
+		parsed, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			return "", fmt.Errorf("date: %w", err)
+		}
+		when = parsed
+	case int:
+		when = time.Unix(int64(v), 0)
+	case int64:
+		when = time.Unix(v, 0)
+	case float64:
+		when = time.Unix(int64(v), 0)
+	default:
+		return "", fmt.Errorf("date: unsupported value %T", t)
+	}
+
+	loc, err := time.LoadLocation(tz)
+	if err != nil {
+		return "", fmt.Errorf("date: %w", err)
+	}
+	return when.In(loc).Format(layout), nil
+}
+
+// speakPhoneNumber spells out a phone number digit by digit, grouped the way
+// people read it aloud, so text-to-speech does not read it as one large
+// number. North American numbers are grouped 3-3-4 without the country code
+// and seven digit local numbers 3-4. Up to four digits are read as one group;
+// other numbers in groups of three, with a last group of two or four so no
+// digit is left on its own.
+func speakPhoneNumber(number string) string {
+	var digits []rune
+	for _, r := range number {
+		if r >= '0' && r <= '9' {
+			digits = append(digits, r)
+		}
+	}
+	if len(digits) == 11 && digits[0] == '1' {
+		digits = digits[1:]
+	}
+
+	var groups []int
+	switch n := len(digits); {
+	case n == 10:
+		groups = []int{3, 3, 4}
+	case n == 7:
+		groups = []int{3, 4}
+	case n <= 4:
+		groups = []int{n}
+	default:
+		for ; n > 4; n -= 3 {
+			groups = append(groups, 3)
+		}
+		groups = append(groups, n)
+	}
+
+	var out []string
+	i := 0
+	for _, size := range groups {
+		group := make([]string, size)
+		for j := range group {
+			group[j] = string(digits[i])
+			i++
+		}
+		out = append(out, strings.Join(group, " "))
+	}
+	return strings.Join(out, ", ")
+}
+
+var (
+	smallNumbers = []string{
+		"zero", "one", "two", "three", "four", "five", "six", "seven", "eight", "nine",
+		"ten", "eleven", "twelve", "thirteen", "fourteen", "fifteen", "sixteen",
+		"seventeen", "eighteen", "nineteen",
+	}
+	tensNumbers = []string{"", "", "twenty", "thirty", "forty", "fifty", "sixty", "seventy", "eighty", "ninety"}
+	scaleNames  = []string{"", "thousand", "million", "billion", "trillion", "quadrillion", "quintillion"}
+)
+
+// numberToWords spells out a whole number in English: 1234 becomes
+// "one thousand two hundred thirty-four".
+func numberToWords(v any) (string, error) {
+	var n int64
+	switch t := v.(type) {
+	case int:
+		n = int64(t)
+	case int64:
+		n = t
+	case int32:
+		n = int64(t)
+	case float64:
+		if t != math.Trunc(t) {
+			return "", fmt.Errorf("numberWords: %g is not a whole number", t)
+		}
+		n = int64(t)
+	case string:
+		parsed, err := strconv.ParseInt(t, 10, 64)
+		if err != nil {
+			return "", fmt.Errorf("numberWords: %w", err)
+		}
+		n = parsed
+	default:
+		return "", fmt.Errorf("numberWords: unsupported value %T", v)
+	}
+
+	if n == 0 {
+		return smallNumbers[0], nil
+	}
+	// The magnitude is unsigned so math.MinInt64 can be negated
+	prefix := ""
+	u := uint64(n)
+	if n < 0 {
+		prefix = "minus "
+		u = uint64(-(n + 1)) + 1
+	}
+
+	var parts []string
+	for scale := 0; u > 0; scale++ {
+		chunk := u % 1000
+		u /= 1000
+		if chunk == 0 {
+			continue
+		}
+		words := hundredsToWords(int(chunk))
+		if scaleNames[scale] != "" {
+			words += " " + scaleNames[scale]
+		}
+		parts = append([]string{words}, parts...)
+	}
+	return prefix + strings.Join(parts, " "), nil
+}
+
+func hundredsToWords(n int) string {
+	var parts []string
+	if n >= 100 {
+		parts = append(parts, smallNumbers[n/100]+" hundred")
+		n %= 100
+	}
+	switch {
+	case n >= 20:
+		word := tensNumbers[n/10]
+		if n%10 != 0 {
+			word += "-" + smallNumbers[n%10]
+		}
+		parts = append(parts, word)
+	case n > 0:
+		parts = append(parts, smallNumbers[n])
+	}
+	return strings.Join(parts, " ")
+}
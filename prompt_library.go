@@ -0,0 +1,264 @@
+package vapi
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// PromptLibrary is a set of prompt templates indexed by PromptHeader.Name
+// and Version. Load one from a directory with LoadPromptLibrary or from an
+// embed.FS with LoadPromptLibraryFS:
+//
+//	//go:embed prompts
+//	var promptFS embed.FS
+//
+//	sub, _ := fs.Sub(promptFS, "prompts")
+//	lib, err := vapi.LoadPromptLibraryFS(sub)
+//
+// Prompts of the same name and version can exist once per PromptHeader.Locale;
+// use GetLocale and LatestLocale to pick one for a locale.
+type PromptLibrary struct {
+	// name -> version -> locale
+	prompts map[string]map[string]map[string]Prompt
+	files   map[string]map[string]map[string]string
+}
+
+// PromptExtensions are the file extensions of prompt templates and
+// partials. Other files in a library directory, such as a README.txt or
+// notes.yaml, are skipped. Add to it for other naming schemes.
+var PromptExtensions = []string{".md", ".tmpl", ".prompt"}
+
+// isPromptFile reports whether the file at p has one of PromptExtensions
+func isPromptFile(p string) bool {
+	return contains(PromptExtensions, strings.ToLower(path.Ext(p)))
+}
+
+// LoadPromptLibrary loads every prompt template under dir
+func LoadPromptLibrary(dir string) (*PromptLibrary, error) {
+	return LoadPromptLibraryFS(os.DirFS(dir))
+}
+
+// LoadPromptLibraryFS loads every prompt template in fsys, walking
+// subdirectories. Hidden files and directories, and files without one of
+// PromptExtensions, are skipped. Prompts without a name in their header are
+// named after the file. Two files declaring the same name, version and
+// locale are an error.
+//
+// Files whose name starts with "_" are partials rather than prompts. Those at
+// the root of fsys or under its PartialsDir can be included by every prompt
+// in the library with {{template "name" .}}.
+func LoadPromptLibraryFS(fsys fs.FS) (*PromptLibrary, error) {
+	lib := &PromptLibrary{
+		prompts: map[string]map[string]map[string]Prompt{},
+		files:   map[string]map[string]map[string]string{},
+	}
+
+	partials, err := loadPartials(fsys)
+	if err != nil {
+		return nil, err
+	}
+
+	err = fs.WalkDir(fsys, ".", func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if p != "." && strings.HasPrefix(d.Name(), ".") {
+			if d.IsDir() {
+				return fs.SkipDir
+			}
+			return nil
+		}
+		if d.IsDir() || isPartialFile(p) || !isPromptFile(p) {
+			return nil
+		}
+
+		content, err := fs.ReadFile(fsys, p)
+		if err != nil {
+			return err
+		}
+		prompt, err := parsePromptTemplate(string(content), partials)
+		if err != nil {
+			return fmt.Errorf("failed to load prompt %s: %w", p, err)
+		}
+		if prompt.Header.Name == "" {
+			base := path.Base(p)
+			prompt.Header.Name = strings.TrimSuffix(base, path.Ext(base))
+		}
+		return lib.add(p, prompt)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return lib, nil
+}
+
+func (l *PromptLibrary) add(file string, prompt Prompt) error {
+	name, version := prompt.Header.Name, prompt.Header.Version
+	locale := NormalizeLocale(prompt.Header.Locale)
+	if l.prompts[name] == nil {
+		l.prompts[name] = map[string]map[string]Prompt{}
+		l.files[name] = map[string]map[string]string{}
+	}
+	if l.prompts[name][version] == nil {
+		l.prompts[name][version] = map[string]Prompt{}
+		l.files[name][version] = map[string]string{}
+	}
+	if other, ok := l.files[name][version][locale]; ok {
+		if locale != "" {
+			return fmt.Errorf("duplicate prompt %q version %q locale %q in %s and %s", name, version, locale, other, file)
+		}
+		return fmt.Errorf("duplicate prompt %q version %q in %s and %s", name, version, other, file)
+	}
+	l.prompts[name][version][locale] = prompt
+	l.files[name][version][locale] = file
+	return nil
+}
+
+// Get returns the prompt with the given name and version. When the version
+// exists in several locales, the one for DefaultLocale is returned, and
+// when it exists in none of DefaultLocale's fallbacks, the first of its
+// locales in sorted order.
+func (l *PromptLibrary) Get(name, version string) (Prompt, error) {
+	return l.GetLocale(name, version, "")
+}
+
+// GetLocale returns the prompt with the given name and version in the
+// closest locale from LocaleFallbacks.
+func (l *PromptLibrary) GetLocale(name, version, locale string) (Prompt, error) {
+	for _, loc := range LocaleFallbacks(locale) {
+		if prompt, ok := l.prompts[name][version][loc]; ok {
+			return prompt, nil
+		}
+	}
+	if locale == "" {
+		if prompt, ok := anyLocale(l.prompts[name][version]); ok {
+			return prompt, nil
+		}
+	}
+	if locale != "" {
+		return Prompt{}, fmt.Errorf("prompt %q version %q not found for locale %q", name, version, locale)
+	}
+	return Prompt{}, fmt.Errorf("prompt %q version %q not found", name, version)
+}
+
+// Latest returns the highest version of the named prompt, preferring
+// DefaultLocale as Get does
+func (l *PromptLibrary) Latest(name string) (Prompt, error) {
+	return l.LatestLocale(name, "")
+}
+
+// LatestLocale returns the highest version of the named prompt in the
+// closest locale from LocaleFallbacks. A closer locale wins over a higher
+// version: with es 1.0.0 and en 2.0.0, "es-MX" gets es 1.0.0.
+func (l *PromptLibrary) LatestLocale(name, locale string) (Prompt, error) {
+	versions := l.Versions(name)
+	if len(versions) == 0 {
+		return Prompt{}, fmt.Errorf("prompt %q not found", name)
+	}
+	for _, loc := range LocaleFallbacks(locale) {
+		for i := len(versions) - 1; i >= 0; i-- {
+			if prompt, ok := l.prompts[name][versions[i]][loc]; ok {
+				return prompt, nil
+			}
+		}
+	}
+	if locale == "" {
+		if prompt, ok := anyLocale(l.prompts[name][versions[len(versions)-1]]); ok {
+			return prompt, nil
+		}
+	}
+	return Prompt{}, fmt.Errorf("prompt %q not found for locale %q", name, locale)
+}
+
+// anyLocale returns the prompt in the first locale of byLocale in sorted
+// order, for lookups that did not ask for a locale
+func anyLocale(byLocale map[string]Prompt) (Prompt, bool) {
+	if len(byLocale) == 0 {
+		return Prompt{}, false
+	}
+	locales := make([]string, 0, len(byLocale))
+	for loc := range byLocale {
+		locales = append(locales, loc)
+	}
+	sort.Strings(locales)
+	return byLocale[locales[0]], true
+}
+
+// Locales returns the locales the named prompt exists in, sorted. Prompts
+// without a locale are listed as "".
+func (l *PromptLibrary) Locales(name string) []string {
+	seen := map[string]bool{}
+	for _, byLocale := range l.prompts[name] {
+		for loc := range byLocale {
+			seen[loc] = true
+		}
+	}
+	locales := make([]string, 0, len(seen))
+	for loc := range seen {
+		locales = append(locales, loc)
+	}
+	sort.Strings(locales)
+	return locales
+}
+
+// Names returns the names of all prompts in the library, sorted
+func (l *PromptLibrary) Names() []string {
+	names := make([]string, 0, len(l.prompts))
+	for name := range l.prompts {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Versions returns the versions of the named prompt, lowest first
+func (l *PromptLibrary) Versions(name string) []string {
+	versions := make([]string, 0, len(l.prompts[name]))
+	for v := range l.prompts[name] {
+		versions = append(versions, v)
+	}
+	sort.Slice(versions, func(i, j int) bool {
+		return compareVersions(versions[i], versions[j]) < 0
+	})
+	return versions
+}
+
+// compareVersions compares dotted version strings such as "1.10.0" and
+// "v1.9", numerically where both parts are numbers. It returns -1, 0 or 1.
+func compareVersions(a, b string) int {
+	pa := strings.Split(strings.TrimPrefix(a, "v"), ".")
+	pb := strings.Split(strings.TrimPrefix(b, "v"), ".")
+	for i := 0; i < len(pa) || i < len(pb); i++ {
+		var sa, sb string = "0", "0"
+		if i < len(pa) {
+			sa = pa[i]
+		}
+		if i < len(pb) {
+			sb = pb[i]
+		}
+
+		na, errA := strconv.Atoi(sa)
+		nb, errB := strconv.Atoi(sb)
+		switch {
+		case errA == nil && errB == nil:
+			if na != nb {
+				if na < nb {
+					return -1
+				}
+				return 1
+			}
+		case sa != sb:
+			if sa < sb {
+				return -1
+			}
+			return 1
+		}
+	}
+	return 0
+}
@@ -0,0 +1,550 @@
+package vapi
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"text/template"
+	"text/template/parse"
+	"unicode/utf8"
+
+	"github.com/chriscow/minds"
+)
+
+// Lint rules reported in Diagnostic.Rule
+const (
+	LintFrontMatter       = "front-matter"
+	LintSyntax            = "syntax"
+	LintUnclosedAction    = "unclosed-action"
+	LintUndefinedFunction = "undefined-function"
+	LintUndefinedPartial  = "undefined-partial"
+	LintVapiVariable      = "vapi-variable"
+	LintSingleBrace       = "single-brace"
+	LintForbiddenPhrase   = "forbidden-phrase"
+	LintPromptLength      = "prompt-length"
+)
+
+// LintSeverity is how serious a Diagnostic is
+type LintSeverity string
+
+const (
+	LintError   LintSeverity = "error"
+	LintWarning LintSeverity = "warning"
+)
+
+// Diagnostic is one problem found by LintPrompt. Line and Column are 1-based
+// positions in the prompt file; Column is 0 when only the line is known.
+type Diagnostic struct {
+	Line     int
+	Column   int
+	Severity LintSeverity
+	Rule     string
+	Message  string
+}
+
+func (d Diagnostic) String() string {
+	pos := strconv.Itoa(d.Line)
+	if d.Column > 0 {
+		pos += ":" + strconv.Itoa(d.Column)
+	}
+	return fmt.Sprintf("%s: %s: %s (%s)", pos, d.Severity, d.Message, d.Rule)
+}
+
+// Diagnostics is the result of LintPrompt, ordered by position
+type Diagnostics []Diagnostic
+
+// Err returns an error listing the error-severity diagnostics, or nil if
+// there are only warnings.
+func (ds Diagnostics) Err() error {
+	var msgs []string
+	for _, d := range ds {
+		if d.Severity == LintError {
+			msgs = append(msgs, d.String())
+		}
+	}
+	if len(msgs) == 0 {
+		return nil
+	}
+	return fmt.Errorf("%d prompt lint error(s):\n%s", len(msgs), strings.Join(msgs, "\n"))
+}
+
+// LintOptions configures LintPrompt. The zero value runs every check that
+// needs no configuration.
+type LintOptions struct {
+	// Funcs are template functions available in addition to PromptFuncs
+	Funcs template.FuncMap
+	// Partials are the partials the prompt may include, as returned for a
+	// prompt directory
+	Partials map[string]string
+	// MaxTokens warns when the prompt and the partials it includes are
+	// longer than this many tokens. Zero disables the check.
+	MaxTokens int
+	// Tokenizer counts tokens for MaxTokens. When nil the count is estimated
+	// at four characters per token.
+	Tokenizer minds.TokenCounter
+	// ForbiddenPhrases are reported wherever they appear, ignoring case
+	ForbiddenPhrases []string
+}
+
+// builtinTemplateFuncs are the functions text/template always defines
+var builtinTemplateFuncs = map[string]bool{
+	"and": true, "call": true, "html": true, "index": true, "slice": true,
+	"js": true, "len": true, "not": true, "or": true, "print": true,
+	"printf": true, "println": true, "urlquery": true,
+	"eq": true, "ge": true, "gt": true, "le": true, "lt": true, "ne": true,
+}
+
+var parseErrorLine = regexp.MustCompile(`^template: [^:]*:(\d+): (.*)$`)
+
+// LintPrompt checks a prompt file and returns every problem found. Unlike
+// CreatePromptTemplate it does not stop at the first error and it reports
+// where each problem is. It checks:
+//
+//   - the front matter and template syntax, including unclosed {{ actions
+//   - functions that are not defined and partials that do not exist
+//   - Vapi {{variable}} placeholders written where {{.variable}} was meant
+//   - single { and } outside code spans, JSON examples and \{ escapes
+//   - forbidden phrases and prompts longer than MaxTokens
+func LintPrompt(content string, opts LintOptions) Diagnostics {
+	var ds Diagnostics
+
+	header, body, bodyLine, err := extractFrontMatter(content)
+	if err != nil {
+		d := Diagnostic{Severity: LintError, Rule: LintFrontMatter, Message: err.Error()}
+		var fmErr *FrontMatterError
+		if errors.As(err, &fmErr) {
+			d.Line, d.Message = fmErr.Line, fmErr.Msg
+		}
+		return append(ds, d)
+	}
+
+	l := &linter{body: body, line: bodyLine}
+	l.lint(header.Name, opts)
+	ds = l.diags
+
+	sort.SliceStable(ds, func(i, j int) bool {
+		if ds[i].Line != ds[j].Line {
+			return ds[i].Line < ds[j].Line
+		}
+		return ds[i].Column < ds[j].Column
+	})
+	return ds
+}
+
+// linter collects diagnostics for one prompt body
+type linter struct {
+	body  string
+	line  int // line of the file body starts on
+	diags Diagnostics
+}
+
+// position maps a byte offset in the body to a line and column in the file
+func (l *linter) position(offset int) (int, int) {
+	if offset > len(l.body) {
+		offset = len(l.body)
+	}
+	before := l.body[:offset]
+	lineStart := strings.LastIndexByte(before, '\n') + 1
+	return l.line + strings.Count(before, "\n"), utf8.RuneCountInString(before[lineStart:]) + 1
+}
+
+func (l *linter) report(offset int, severity LintSeverity, rule, format string, args ...any) {
+	line, col := l.position(offset)
+	l.diags = append(l.diags, Diagnostic{Line: line, Column: col, Severity: severity, Rule: rule, Message: fmt.Sprintf(format, args...)})
+}
+
+func (l *linter) lint(name string, opts LintOptions) {
+	l.checkPhrases(opts.ForbiddenPhrases)
+
+	if unclosed := unclosedActions(l.body); len(unclosed) > 0 {
+		for _, offset := range unclosed {
+			l.report(offset, LintError, LintUnclosedAction, "unclosed action, missing }}")
+		}
+		return
+	}
+
+	if name == "" {
+		name = "prompt"
+	}
+	// Sections declared with {{define}} end up in trees next to the root
+	trees := map[string]*parse.Tree{}
+	tree := parse.New(name)
+	tree.Mode = parse.SkipFuncCheck
+	if _, err := tree.Parse(l.body, "", "", trees); err != nil {
+		d := Diagnostic{Severity: LintError, Rule: LintSyntax, Message: err.Error()}
+		if m := parseErrorLine.FindStringSubmatch(err.Error()); m != nil {
+			n, _ := strconv.Atoi(m[1])
+			d.Line, d.Message = l.line+n-1, m[2]
+		}
+		l.diags = append(l.diags, d)
+		return
+	}
+
+	funcs := PromptFuncs()
+	for k, v := range opts.Funcs {
+		funcs[k] = v
+	}
+	templates := map[string]bool{}
+	for name := range opts.Partials {
+		templates[name] = true
+	}
+	for name := range trees {
+		templates[name] = true
+	}
+	for _, t := range trees {
+		l.checkTree(t.Root, funcs, templates)
+		for _, p := range singleBraces(t.Root, l.body) {
+			l.report(p.offset, LintError, LintSingleBrace, "%s", p.msg)
+		}
+	}
+
+	if opts.MaxTokens > 0 {
+		text := l.body
+		included := map[string]bool{}
+		for _, t := range trees {
+			for _, p := range includedPartials(t.Root, opts.Partials) {
+				if !included[p] {
+					included[p] = true
+					text += "\n" + opts.Partials[p]
+				}
+			}
+		}
+		tokens, err := countTokens(opts.Tokenizer, text)
+		switch {
+		case err != nil:
+			l.diags = append(l.diags, Diagnostic{Line: l.line, Severity: LintWarning, Rule: LintPromptLength, Message: fmt.Sprintf("failed to count tokens: %v", err)})
+		case tokens > opts.MaxTokens:
+			l.diags = append(l.diags, Diagnostic{Line: l.line, Severity: LintWarning, Rule: LintPromptLength, Message: fmt.Sprintf("prompt is %d tokens, limit is %d", tokens, opts.MaxTokens)})
+		}
+	}
+}
+
+func (l *linter) checkPhrases(phrases []string) {
+	lower := strings.ToLower(l.body)
+	for _, phrase := range phrases {
+		if phrase == "" {
+			continue
+		}
+		p := strings.ToLower(phrase)
+		for i := 0; ; {
+			j := strings.Index(lower[i:], p)
+			if j < 0 {
+				break
+			}
+			l.report(i+j, LintError, LintForbiddenPhrase, "forbidden phrase %q", phrase)
+			i += j + len(p)
+		}
+	}
+}
+
+// checkTree reports undefined functions and partials and Vapi-style
+// variables in the actions under node.
+func (l *linter) checkTree(node parse.Node, funcs template.FuncMap, templates map[string]bool) {
+	defined := func(name string) bool {
+		_, ok := funcs[name]
+		return ok || builtinTemplateFuncs[name]
+	}
+
+	var walk func(node parse.Node)
+	walk = func(node parse.Node) {
+		switch n := node.(type) {
+		case *parse.ListNode:
+			if n == nil {
+				return
+			}
+			for _, c := range n.Nodes {
+				walk(c)
+			}
+		case *parse.ActionNode:
+			// {{name}} or {{customer.number}} on its own is how Vapi writes
+			// variables; as a template it is a call to a function
+			if v, ok := vapiStyleVariable(n.Pipe); ok && (!defined(v) || vapiBuiltinVariables[v]) {
+				l.report(l.actionStart(n.Position()), LintError, LintVapiVariable,
+					"%s is Vapi variable syntax; use {{.%s}} for template data, or {{`%s`}} to leave it for Vapi",
+					l.actionText(n), vapiVariableName(n.Pipe), l.actionText(n))
+				return
+			}
+			walk(n.Pipe)
+		case *parse.PipeNode:
+			if n == nil {
+				return
+			}
+			for _, cmd := range n.Cmds {
+				walk(cmd)
+			}
+		case *parse.CommandNode:
+			for _, arg := range n.Args {
+				walk(arg)
+			}
+		case *parse.ChainNode:
+			walk(n.Node)
+		case *parse.IdentifierNode:
+			if !defined(n.Ident) {
+				l.report(int(n.Position()), LintError, LintUndefinedFunction, "function %q not defined", n.Ident)
+			}
+		case *parse.IfNode:
+			walk(n.Pipe)
+			walk(n.List)
+			walk(n.ElseList)
+		case *parse.RangeNode:
+			walk(n.Pipe)
+			walk(n.List)
+			walk(n.ElseList)
+		case *parse.WithNode:
+			walk(n.Pipe)
+			walk(n.List)
+			walk(n.ElseList)
+		case *parse.TemplateNode:
+			walk(n.Pipe)
+			if !templates[n.Name] {
+				l.report(l.actionStart(n.Position()), LintError, LintUndefinedPartial, "template includes undefined partial %q", n.Name)
+			}
+		}
+	}
+	walk(node)
+}
+
+// actionStart returns the offset of the {{ opening the action at pos
+func (l *linter) actionStart(pos parse.Pos) int {
+	if start := strings.LastIndex(l.body[:pos], "{{"); start >= 0 {
+		return start
+	}
+	return int(pos)
+}
+
+// actionText returns the source of the action n
+func (l *linter) actionText(n *parse.ActionNode) string {
+	end := strings.Index(l.body[n.Position():], "}}")
+	if end < 0 {
+		return n.String()
+	}
+	return l.body[l.actionStart(n.Position()) : int(n.Position())+end+2]
+}
+
+// vapiStyleVariable reports whether pipe is a lone identifier, optionally
+// followed by fields, and returns the identifier.
+func vapiStyleVariable(pipe *parse.PipeNode) (string, bool) {
+	if pipe == nil || len(pipe.Decl) > 0 || len(pipe.Cmds) != 1 || len(pipe.Cmds[0].Args) != 1 {
+		return "", false
+	}
+	switch arg := pipe.Cmds[0].Args[0].(type) {
+	case *parse.IdentifierNode:
+		return arg.Ident, true
+	case *parse.ChainNode:
+		if id, ok := arg.Node.(*parse.IdentifierNode); ok {
+			return id.Ident, true
+		}
+	}
+	return "", false
+}
+
+// vapiVariableName returns the dotted variable name of a Vapi-style pipe
+func vapiVariableName(pipe *parse.PipeNode) string {
+	switch arg := pipe.Cmds[0].Args[0].(type) {
+	case *parse.IdentifierNode:
+		return arg.Ident
+	case *parse.ChainNode:
+		return arg.Node.String() + "." + strings.Join(arg.Field, ".")
+	}
+	return ""
+}
+
+// includedPartials returns the partials node includes, directly or through
+// other partials.
+func includedPartials(node parse.Node, partials map[string]string) []string {
+	var names []string
+	seen := map[string]bool{}
+	var visit func(node parse.Node)
+	visit = func(node parse.Node) {
+		for _, name := range templateRefs(node) {
+			content, ok := partials[name]
+			if !ok || seen[name] {
+				continue
+			}
+			seen[name] = true
+			names = append(names, name)
+			t := parse.New(name)
+			t.Mode = parse.SkipFuncCheck
+			if _, err := t.Parse(content, "", "", map[string]*parse.Tree{}); err == nil {
+				visit(t.Root)
+			}
+		}
+	}
+	visit(node)
+	sort.Strings(names)
+	return names
+}
+
+// countTokens counts the tokens in text with tokenizer, or estimates them
+// at four characters per token.
+func countTokens(tokenizer minds.TokenCounter, text string) (int, error) {
+	if tokenizer != nil {
+		return tokenizer.CountTokens(text)
+	}
+	return (utf8.RuneCountInString(text) + 3) / 4, nil
+}
+
+// unclosedActions returns the offsets of the {{ in s that have no matching
+// }}. Quoted strings inside an action may contain braces.
+func unclosedActions(s string) []int {
+	var unclosed []int
+	for i := 0; i < len(s); {
+		start := strings.Index(s[i:], "{{")
+		if start < 0 {
+			break
+		}
+		start += i
+		end, ok := actionEnd(s, start+2)
+		if !ok {
+			unclosed = append(unclosed, start)
+			i = start + 2
+			continue
+		}
+		i = end
+	}
+	return unclosed
+}
+
+// actionEnd returns the offset just past the }} closing the action whose
+// contents start at i. It fails if another {{ starts first.
+func actionEnd(s string, i int) (int, bool) {
+	for i < len(s) {
+		switch c := s[i]; {
+		case c == '"' || c == '\'' || c == '`':
+			j := i + 1
+			for j < len(s) && s[j] != c && (c == '`' || s[j] != '\n') {
+				if s[j] == '\\' && c != '`' {
+					j++
+				}
+				j++
+			}
+			i = j + 1
+		case strings.HasPrefix(s[i:], "}}"):
+			return i + 2, true
+		case strings.HasPrefix(s[i:], "{{"):
+			return 0, false
+		default:
+			i++
+		}
+	}
+	return 0, false
+}
+
+type braceProblem struct {
+	offset int
+	msg    string
+}
+
+// singleBraces finds single { and } in the literal text of the template.
+// Braces inside fenced code blocks, `code spans` and JSON examples, and
+// braces escaped with a backslash, are allowed. Braces produced by actions
+// such as {{"{"}} are not text and are never reported.
+func singleBraces(root *parse.ListNode, body string) []braceProblem {
+	code := codeRegions(body)
+	inCode := func(offset int) bool {
+		for _, r := range code {
+			if offset >= r[0] && offset < r[1] {
+				return true
+			}
+		}
+		return false
+	}
+
+	var problems []braceProblem
+	var walk func(node parse.Node)
+	walk = func(node parse.Node) {
+		switch n := node.(type) {
+		case *parse.ListNode:
+			if n == nil {
+				return
+			}
+			for _, c := range n.Nodes {
+				walk(c)
+			}
+		case *parse.IfNode:
+			walk(n.List)
+			walk(n.ElseList)
+		case *parse.RangeNode:
+			walk(n.List)
+			walk(n.ElseList)
+		case *parse.WithNode:
+			walk(n.List)
+			walk(n.ElseList)
+		case *parse.TextNode:
+			text, base := string(n.Text), int(n.Position())
+			for i := 0; i < len(text); i++ {
+				c := text[i]
+				if (c != '{' && c != '}') || inCode(base+i) || (i > 0 && text[i-1] == '\\') {
+					continue
+				}
+				if c == '{' {
+					if n := jsonObjectLen(text[i:]); n > 0 {
+						i += n - 1
+						continue
+					}
+					problems = append(problems, braceProblem{base + i, "template contains single left curly brace '{', use '{{' instead"})
+				} else {
+					problems = append(problems, braceProblem{base + i, "template contains single right curly brace '}', use '}}' instead"})
+				}
+			}
+		}
+	}
+	walk(root)
+	return problems
+}
+
+// jsonObjectLen returns the length of the JSON object s starts with, or 0
+func jsonObjectLen(s string) int {
+	dec := json.NewDecoder(strings.NewReader(s))
+	var obj map[string]any
+	if err := dec.Decode(&obj); err != nil {
+		return 0
+	}
+	return int(dec.InputOffset())
+}
+
+// codeRegions returns the [start, end) offsets of the fenced code blocks
+// and inline code spans in s.
+func codeRegions(s string) [][2]int {
+	var regions [][2]int
+	for i := 0; i < len(s); {
+		if s[i] != '`' {
+			i++
+			continue
+		}
+		// A run of backticks is closed by a run of the same length
+		n := 1
+		for i+n < len(s) && s[i+n] == '`' {
+			n++
+		}
+		fence := s[i : i+n]
+		end := -1
+		for j := i + n; j < len(s); {
+			k := strings.Index(s[j:], fence)
+			if k < 0 {
+				break
+			}
+			k += j
+			if k+n < len(s) && s[k+n] == '`' {
+				j = k + n + 1
+				for j < len(s) && s[j] == '`' {
+					j++
+				}
+				continue
+			}
+			end = k + n
+			break
+		}
+		if end < 0 {
+			i += n
+			continue
+		}
+		regions = append(regions, [2]int{i, end})
+		i = end
+	}
+	return regions
+}
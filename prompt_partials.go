@@ -0,0 +1,180 @@
+package vapi
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io/fs"
+	"path"
+	"sort"
+	"strings"
+	"text/template"
+	"text/template/parse"
+)
+
+// isPartialFile reports whether the file at p is a partial. Partials are
+// files whose name starts with an underscore, e.g. _compliance.md, and are
+// included from prompts with {{template "compliance" .}}.
+func isPartialFile(p string) bool {
+	return strings.HasPrefix(path.Base(p), "_")
+}
+
+// PartialsDir is the subdirectory of a prompt directory that holds shared
+// partials, in addition to the ones next to the prompts themselves.
+const PartialsDir = "partials"
+
+// loadPartials reads the partials in fsys, keyed by name. The name is the
+// header name if there is one, otherwise the file name without the leading
+// underscore and extension. Partials are the ones at the root of fsys and
+// anywhere under its PartialsDir; other subdirectories are not searched, so
+// every loader resolves partials the same way without walking unrelated
+// files.
+func loadPartials(fsys fs.FS) (map[string]string, error) {
+	partials := map[string]string{}
+	files := map[string]string{}
+
+	load := func(p string) error {
+		if !isPartialFile(p) || !isPromptFile(p) {
+			return nil
+		}
+
+		content, err := fs.ReadFile(fsys, p)
+		if err != nil {
+			return err
+		}
+		header, body, _, err := extractFrontMatter(string(content))
+		if err != nil {
+			return fmt.Errorf("failed to load partial %s: %w", p, err)
+		}
+
+		name := header.Name
+		if name == "" {
+			base := strings.TrimPrefix(path.Base(p), "_")
+			name = strings.TrimSuffix(base, path.Ext(base))
+		}
+		if other, ok := files[name]; ok {
+			return fmt.Errorf("duplicate partial %q in %s and %s", name, other, p)
+		}
+		partials[name] = body
+		files[name] = p
+		return nil
+	}
+
+	entries, err := fs.ReadDir(fsys, ".")
+	if err != nil {
+		return nil, err
+	}
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		if err := load(entry.Name()); err != nil {
+			return nil, err
+		}
+	}
+
+	if info, err := fs.Stat(fsys, PartialsDir); err != nil || !info.IsDir() {
+		return partials, nil
+	}
+	err = fs.WalkDir(fsys, PartialsDir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if strings.HasPrefix(d.Name(), ".") {
+				return fs.SkipDir
+			}
+			return nil
+		}
+		return load(p)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return partials, nil
+}
+
+// usedPartials returns the sorted names of the partials tmpl includes,
+// directly or through other templates.
+func usedPartials(tmpl *template.Template, partials map[string]string) ([]string, error) {
+	seen := map[string]bool{}
+	var used []string
+
+	var visit func(node parse.Node) error
+	visit = func(node parse.Node) error {
+		for _, name := range templateRefs(node) {
+			if seen[name] {
+				continue
+			}
+			seen[name] = true
+
+			t := tmpl.Lookup(name)
+			if t == nil || t.Tree == nil {
+				return fmt.Errorf("template includes undefined partial %q", name)
+			}
+			if _, ok := partials[name]; ok {
+				used = append(used, name)
+			}
+			if err := visit(t.Tree.Root); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	if tmpl.Tree != nil {
+		if err := visit(tmpl.Tree.Root); err != nil {
+			return nil, err
+		}
+	}
+	sort.Strings(used)
+	return used, nil
+}
+
+// templateRefs returns the names of the templates invoked under node
+func templateRefs(node parse.Node) []string {
+	var refs []string
+	var walk func(n parse.Node)
+	walk = func(n parse.Node) {
+		switch n := n.(type) {
+		case *parse.ListNode:
+			if n == nil {
+				return
+			}
+			for _, c := range n.Nodes {
+				walk(c)
+			}
+		case *parse.TemplateNode:
+			refs = append(refs, n.Name)
+		case *parse.IfNode:
+			walk(n.List)
+			walk(n.ElseList)
+		case *parse.RangeNode:
+			walk(n.List)
+			walk(n.ElseList)
+		case *parse.WithNode:
+			walk(n.List)
+			walk(n.ElseList)
+		}
+	}
+	walk(node)
+	return refs
+}
+
+// promptContentHash hashes the prompt body together with the partials it
+// uses, so editing a partial changes the hash of every prompt including it.
+// Prompts without partials hash to the SHA256 of the body alone.
+func promptContentHash(body string, used []string, partials map[string]string) (string, error) {
+	if len(used) == 0 {
+		return sHA256Hash([]byte(body))
+	}
+
+	h := sha256.New()
+	h.Write([]byte(body))
+	for _, name := range used {
+		h.Write([]byte("\x00" + name + "\x00"))
+		h.Write([]byte(partials[name]))
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
@@ -0,0 +1,202 @@
+package vapi
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Sections a prompt file can define with {{define "name"}}...{{end}}. The
+// part of the file outside any define is the system prompt unless a
+// "system" section is defined explicitly.
+const (
+	PromptSectionSystem            = "system"
+	PromptSectionFirstMessage      = "firstMessage"
+	PromptSectionEndCallMessage    = "endCallMessage"
+	PromptSectionVoicemailMessage  = "voicemailMessage"
+	PromptSectionSummary           = "summary"
+	PromptSectionStructuredData    = "structuredData"
+	PromptSectionSuccessEvaluation = "successEvaluation"
+)
+
+// Metadata keys ApplyToAssistant records on the assistant, so every call can
+// be traced back to the prompt version it ran with.
+const (
+	MetadataPromptName    = "promptName"
+	MetadataPromptVersion = "promptVersion"
+	MetadataPromptSHA256  = "promptSha256"
+	MetadataPromptLocale  = "promptLocale"
+)
+
+var promptSections = []string{
+	PromptSectionSystem,
+	PromptSectionFirstMessage,
+	PromptSectionEndCallMessage,
+	PromptSectionVoicemailMessage,
+	PromptSectionSummary,
+	PromptSectionStructuredData,
+	PromptSectionSuccessEvaluation,
+}
+
+func isPromptSection(name string) bool {
+	for _, section := range promptSections {
+		if name == section {
+			return true
+		}
+	}
+	return false
+}
+
+// transcriptMessage is appended to analysis plan messages so the analysis
+// model gets the call transcript, as in Vapi's default plans.
+var transcriptMessage = ModelMessage{Role: "user", Content: "Here is the transcript:\n\n{{transcript}}\n\n"}
+
+// systemPromptMessage follows the transcript in success evaluation plans,
+// as in Vapi's default plan
+var systemPromptMessage = ModelMessage{Role: "user", Content: "Here was the system prompt of the call:\n\n{{systemPrompt}}\n\n"}
+
+// Sections returns the names of the sections the prompt defines
+func (p Prompt) Sections() []string {
+	var sections []string
+	for _, name := range promptSections {
+		if p.hasSection(name) {
+			sections = append(sections, name)
+		}
+	}
+	return sections
+}
+
+func (p Prompt) hasSection(name string) bool {
+	if p.Template == nil {
+		return false
+	}
+	if p.sections != nil && !p.sections[name] {
+		return false
+	}
+	t := p.Template.Lookup(name)
+	return t != nil && t.Tree != nil
+}
+
+// ExecuteSection renders a single named section
+func (p Prompt) ExecuteSection(name string, data any) (string, error) {
+	var result strings.Builder
+	if p.html != nil {
+		if err := p.html.ExecuteTemplate(&result, name, data); err != nil {
+			return "", err
+		}
+	} else if err := p.Template.ExecuteTemplate(&result, name, data); err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(result.String()), nil
+}
+
+// ApplyToAssistant renders every section of the prompt into a: the system
+// message of the model (an error if a has no model), the first, end-call
+// and voicemail messages, and the summary, structured-data and success
+// evaluation plan messages (followed by a user message carrying
+// {{transcript}}). Sections the prompt does not define leave a unchanged.
+// The prompt's name, version, SHA256 and locale are recorded in a.Metadata.
+func (p Prompt) ApplyToAssistant(a *Assistant, data any) error {
+	if len(p.Header.Inputs) > 0 {
+		if err := p.CheckData(data).Err(); err != nil {
+			return fmt.Errorf("prompt %q: %w", p.Header.Name, err)
+		}
+	}
+
+	render := func(name string) (string, bool, error) {
+		if !p.hasSection(name) {
+			return "", false, nil
+		}
+		s, err := p.ExecuteSection(name, data)
+		if err != nil {
+			return "", false, fmt.Errorf("section %q: %w", name, err)
+		}
+		return s, true, nil
+	}
+
+	system, ok, err := render(PromptSectionSystem)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		if system, err = p.Execute(data); err != nil {
+			return err
+		}
+		system = strings.TrimSpace(system)
+	}
+	if system != "" {
+		// A model made up here would replace the provider, model and
+		// temperature of a saved assistant when a is a set of overrides
+		if a.Model == nil {
+			return fmt.Errorf("prompt %q: the assistant has no model to set the system prompt on; provide one", p.Header.Name)
+		}
+		messages := []ModelMessage{{Role: "system", Content: system}}
+		for _, m := range a.Model.Messages {
+			if m.Role != "system" {
+				messages = append(messages, m)
+			}
+		}
+		a.Model.Messages = messages
+	}
+
+	for name, field := range map[string]**string{
+		PromptSectionFirstMessage:     &a.FirstMessage,
+		PromptSectionEndCallMessage:   &a.EndCallMessage,
+		PromptSectionVoicemailMessage: &a.VoicemailMessage,
+	} {
+		s, ok, err := render(name)
+		if err != nil {
+			return err
+		}
+		if ok {
+			*field = &s
+		}
+	}
+
+	summary, hasSummary, err := render(PromptSectionSummary)
+	if err != nil {
+		return err
+	}
+	structured, hasStructured, err := render(PromptSectionStructuredData)
+	if err != nil {
+		return err
+	}
+	evaluation, hasEvaluation, err := render(PromptSectionSuccessEvaluation)
+	if err != nil {
+		return err
+	}
+	if hasSummary || hasStructured || hasEvaluation {
+		if a.AnalysisPlan == nil {
+			a.AnalysisPlan = &AnalysisPlan{}
+		}
+	}
+	if hasSummary {
+		if a.AnalysisPlan.SummaryPlan == nil {
+			a.AnalysisPlan.SummaryPlan = &SummaryPlan{}
+		}
+		a.AnalysisPlan.SummaryPlan.Messages = []ModelMessage{{Role: "system", Content: summary}, transcriptMessage}
+	}
+	if hasStructured {
+		if a.AnalysisPlan.StructuredDataPlan == nil {
+			a.AnalysisPlan.StructuredDataPlan = &StructuredDataPlan{Enabled: true}
+		}
+		a.AnalysisPlan.StructuredDataPlan.Messages = []ModelMessage{{Role: "system", Content: structured}, transcriptMessage}
+	}
+	if hasEvaluation {
+		if a.AnalysisPlan.SuccessEvaluationPlan == nil {
+			a.AnalysisPlan.SuccessEvaluationPlan = &SuccessEvaluationPlan{}
+		}
+		a.AnalysisPlan.SuccessEvaluationPlan.Messages = []ModelMessage{{Role: "system", Content: evaluation}, transcriptMessage, systemPromptMessage}
+	}
+
+	if a.Metadata == nil {
+		a.Metadata = map[string]any{}
+	}
+	a.Metadata[MetadataPromptName] = p.Header.Name
+	a.Metadata[MetadataPromptVersion] = p.Header.Version
+	a.Metadata[MetadataPromptSHA256] = p.Header.SHA256
+	if p.Header.Locale != "" {
+		a.Metadata[MetadataPromptLocale] = NormalizeLocale(p.Header.Locale)
+	}
+
+	return nil
+}
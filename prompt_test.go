@@ -0,0 +1,697 @@
+package vapi
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestLoadPromptLibraryFS(t *testing.T) {
+	fsys := fstest.MapFS{
+		"greeting-1.md":      {Data: []byte("---\nname: greeting\nversion: 1.9.0\n---\nHello {{.Name}}")},
+		"greeting-2.md":      {Data: []byte("---\nname: greeting\nversion: 1.10.0\n---\nHi {{.Name}}")},
+		"sales/closing.md":   {Data: []byte("Thanks for your time.")},
+		".hidden/ignored.md": {Data: []byte("{{")},
+		"README.txt":         {Data: []byte("Prompts use {{.Name}} and {{template ...}}.")},
+		"sales/notes.yaml":   {Data: []byte("owner: {{")},
+	}
+
+	lib, err := LoadPromptLibraryFS(fsys)
+	if err != nil {
+		t.Fatalf("LoadPromptLibraryFS() error = %v", err)
+	}
+
+	if got := strings.Join(lib.Names(), ","); got != "closing,greeting" {
+		t.Errorf("Names() = %s", got)
+	}
+
+	latest, err := lib.Latest("greeting")
+	if err != nil {
+		t.Fatalf("Latest() error = %v", err)
+	}
+	if latest.Header.Version != "1.10.0" {
+		t.Errorf("Latest() version = %s, want 1.10.0", latest.Header.Version)
+	}
+
+	p, err := lib.Get("greeting", "1.9.0")
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	out, err := p.Execute(map[string]string{"Name": "Pat"})
+	if err != nil || out != "Hello Pat" {
+		t.Errorf("Execute() = %q, %v", out, err)
+	}
+
+	if _, err := lib.Get("greeting", "2.0.0"); err == nil {
+		t.Error("Get() expected error for missing version")
+	}
+}
+
+func TestLoadPromptLibraryFS_Duplicate(t *testing.T) {
+	fsys := fstest.MapFS{
+		"a.md": {Data: []byte("---\nname: greeting\nversion: 1.0.0\n---\nHello")},
+		"b.md": {Data: []byte("---\nname: greeting\nversion: 1.0.0\n---\nHi")},
+	}
+
+	_, err := LoadPromptLibraryFS(fsys)
+	if err == nil || !strings.Contains(err.Error(), "a.md and b.md") {
+		t.Errorf("LoadPromptLibraryFS() error = %v, want duplicate error naming both files", err)
+	}
+}
+
+func TestPrompt_TextRenderingAndFuncs(t *testing.T) {
+	body := `Hello {{.Name | default "there"}} & welcome, {{title .Company}}.
+Call {{phone .Number}} about your {{numberWords .Count}} orders: {{join ", " .Items}}.
+Due {{date "Jan 2 3:04PM" "America/New_York" .Due}}.`
+
+	p, err := parsePromptTemplate(body, nil)
+	if err != nil {
+		t.Fatalf("parsePromptTemplate() error = %v", err)
+	}
+
+	got, err := p.Execute(map[string]any{
+		"Name":    "",
+		"Company": "o'brien & sons",
+		"Number":  "+14155550123",
+		"Count":   42,
+		"Items":   []string{"<lamp>", "desk"},
+		"Due":     "2024-05-01T18:30:00Z",
+	})
+	if err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+
+	want := `Hello there & welcome, O'brien & Sons.
+Call 4 1 5, 5 5 5, 0 1 2 3 about your forty-two orders: <lamp>, desk.
+Due May 1 2:30PM.`
+	if got != want {
+		t.Errorf("Execute() =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestPrompt_HTMLEscapeOptIn(t *testing.T) {
+	p, err := parsePromptTemplate("---\nescape: html\n---\nHi {{.Name}}", nil)
+	if err != nil {
+		t.Fatalf("parsePromptTemplate() error = %v", err)
+	}
+	got, err := p.Execute(map[string]string{"Name": "O'Brien"})
+	if err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	if got != "Hi O&#39;Brien" {
+		t.Errorf("Execute() = %q", got)
+	}
+}
+
+func TestNumberToWords(t *testing.T) {
+	if got, err := numberToWords(int64(math.MinInt64)); err != nil || !strings.HasPrefix(got, "minus nine quintillion two hundred twenty-three quadrillion") || !strings.HasSuffix(got, "eight hundred eight") {
+		t.Errorf("numberToWords(MinInt64) = %q, %v", got, err)
+	}
+
+	tests := map[int]string{
+		0:       "zero",
+		7:       "seven",
+		15:      "fifteen",
+		100:     "one hundred",
+		1234:    "one thousand two hundred thirty-four",
+		-90:     "minus ninety",
+		2000005: "two million five",
+	}
+	for n, want := range tests {
+		got, err := numberToWords(n)
+		if err != nil || got != want {
+			t.Errorf("numberToWords(%d) = %q, %v; want %q", n, got, err, want)
+		}
+	}
+}
+
+func TestSpeakPhoneNumber(t *testing.T) {
+	tests := map[string]string{
+		"+1 (415) 555-0123": "4 1 5, 5 5 5, 0 1 2 3",
+		"4155550123":        "4 1 5, 5 5 5, 0 1 2 3",
+		"555-0123":          "5 5 5, 0 1 2 3",
+		"911":               "9 1 1",
+		"0123":              "0 1 2 3",
+		"12345":             "1 2 3, 4 5",
+		"12345678":          "1 2 3, 4 5 6, 7 8",
+		"+44 20 7946 0958":  "4 4 2, 0 7 9, 4 6 0, 9 5 8",
+		"+49 30 1234567":    "4 9 3, 0 1 2, 3 4 5, 6 7",
+		"+353 1 234 5678":   "3 5 3, 1 2 3, 4 5 6, 7 8",
+		"":                  "",
+	}
+	for in, want := range tests {
+		if got := speakPhoneNumber(in); got != want {
+			t.Errorf("speakPhoneNumber(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestPromptPartials(t *testing.T) {
+	fsys := fstest.MapFS{
+		"_compliance.md":       {Data: []byte("This call may be recorded.")},
+		"partials/_persona.md": {Data: []byte("---\nname: persona\n---\nYou are {{.Agent}}. {{template \"compliance\" .}}")},
+		"intro.md":             {Data: []byte("---\nname: intro\n---\n{{template \"persona\" .}} Hello!")},
+		"plain.md":             {Data: []byte("---\nname: plain\n---\nHello!")},
+		"drafts/_persona.md":   {Data: []byte("Partials outside partials/ are not loaded.")},
+	}
+
+	lib, err := LoadPromptLibraryFS(fsys)
+	if err != nil {
+		t.Fatalf("LoadPromptLibraryFS() error = %v", err)
+	}
+	if got := strings.Join(lib.Names(), ","); got != "intro,plain" {
+		t.Errorf("Names() = %s, partials should not be listed", got)
+	}
+
+	intro, _ := lib.Latest("intro")
+	out, err := intro.Execute(map[string]string{"Agent": "Alex"})
+	if err != nil || out != "You are Alex. This call may be recorded. Hello!" {
+		t.Errorf("Execute() = %q, %v", out, err)
+	}
+
+	plain, _ := lib.Latest("plain")
+	if want, _ := sHA256Hash([]byte("Hello!")); plain.Header.SHA256 != want {
+		t.Errorf("prompt without partials hash = %s, want hash of body", plain.Header.SHA256)
+	}
+
+	// Pin the hash, then edit a nested partial: the prompt must notice
+	fsys["intro.md"] = &fstest.MapFile{Data: []byte("---\nname: intro\nsha256: " + intro.Header.SHA256 + "\n---\n{{template \"persona\" .}} Hello!")}
+	if _, err := LoadPromptLibraryFS(fsys); err != nil {
+		t.Fatalf("LoadPromptLibraryFS() with pinned hash error = %v", err)
+	}
+	fsys["_compliance.md"] = &fstest.MapFile{Data: []byte("This call is recorded.")}
+	if _, err := LoadPromptLibraryFS(fsys); err == nil || !strings.Contains(err.Error(), "SHA256 mismatch") {
+		t.Errorf("LoadPromptLibraryFS() after partial edit error = %v, want SHA256 mismatch", err)
+	}
+
+	// A single file resolves partials the same way the library does
+	dir := t.TempDir()
+	for name, f := range fsys {
+		os.MkdirAll(filepath.Join(dir, filepath.Dir(name)), 0o755)
+		if err := os.WriteFile(filepath.Join(dir, name), f.Data, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := CreatePromptTemplate(filepath.Join(dir, "intro.md")); err == nil || !strings.Contains(err.Error(), "SHA256 mismatch") {
+		t.Errorf("CreatePromptTemplate() with nested partial error = %v, want SHA256 mismatch", err)
+	}
+
+	delete(fsys, "intro.md")
+	fsys["broken.md"] = &fstest.MapFile{Data: []byte("{{template \"missing\" .}}")}
+	if _, err := LoadPromptLibraryFS(fsys); err == nil || !strings.Contains(err.Error(), "undefined partial") {
+		t.Error("LoadPromptLibraryFS() expected error for undefined partial")
+	}
+}
+
+func TestPrompt_VariablesAndCheckData(t *testing.T) {
+	body := `---
+name: followup
+inputs:
+  - name: Customer
+    type: map
+    required: true
+  - name: Count
+    type: integer
+  - name: Notes
+    type: string
+---
+Hi {{.Customer.Name}}, about your {{.Count}} orders.
+{{range .Items}}- {{.Title}}
+{{end}}{{with $.Notes}}Notes: {{.}}{{end}}`
+
+	p, err := parsePromptTemplate(body, nil)
+	if err != nil {
+		t.Fatalf("parsePromptTemplate() error = %v", err)
+	}
+
+	want := "Count,Customer.Name,Items,Notes"
+	if got := strings.Join(p.Variables(), ","); got != want {
+		t.Errorf("Variables() = %s, want %s", got, want)
+	}
+
+	report := p.CheckData(map[string]any{"Count": 2.5, "Extra": true})
+	if got := strings.Join(report.Missing, ","); got != "Customer,Items" {
+		t.Errorf("Missing = %s", got)
+	}
+	if got := strings.Join(report.Unused, ","); got != "Extra" {
+		t.Errorf("Unused = %s", got)
+	}
+	if len(report.Invalid) != 1 || !strings.HasPrefix(report.Invalid[0], "Count") {
+		t.Errorf("Invalid = %v", report.Invalid)
+	}
+
+	if _, err := p.Execute(map[string]any{"Count": 1}); err == nil {
+		t.Error("Execute() expected error for missing required input")
+	}
+	out, err := p.Execute(map[string]any{
+		"Customer": map[string]string{"Name": "Pat"},
+		"Count":    1,
+		"Items":    []map[string]string{{"Title": "Lamp"}},
+		"Notes":    "fragile",
+	})
+	if err != nil || out != "Hi Pat, about your 1 orders.\n- Lamp\nNotes: fragile" {
+		t.Errorf("Execute() = %q, %v", out, err)
+	}
+}
+
+func TestAssistant_CheckVariableValues(t *testing.T) {
+	first := "Hi {{name}}, it's {{ date }}. Calling {{customer.number}} about {{order.id}}."
+	a := &Assistant{
+		FirstMessage:   &first,
+		Model:          &ModelConfig{Messages: []ModelMessage{{Role: "system", Content: "Help {{name}} with {{topic}}."}}},
+		VariableValues: map[string]any{"name": "Pat", "order": map[string]any{"id": 1}, "unused": 1},
+	}
+
+	report := a.CheckVariableValues()
+	if got := strings.Join(report.Missing, ","); got != "topic" {
+		t.Errorf("Missing = %s, want topic", got)
+	}
+	if got := strings.Join(report.Unused, ","); got != "unused" {
+		t.Errorf("Unused = %s, want unused", got)
+	}
+}
+
+func TestPrompt_ApplyToAssistant(t *testing.T) {
+	body := `---
+name: support
+version: 1.2.0
+---
+You are a support agent for {{.Company}}.
+{{define "firstMessage"}}
+Hi, this is {{.Company}} support.
+{{end}}
+{{define "voicemailMessage"}}Please call {{.Company}} back.{{end}}
+{{define "summary"}}Summarize the support call in two sentences.{{end}}`
+
+	p, err := parsePromptTemplate(body, nil)
+	if err != nil {
+		t.Fatalf("parsePromptTemplate() error = %v", err)
+	}
+
+	if got, want := strings.Join(p.Sections(), ","), "firstMessage,voicemailMessage,summary"; got != want {
+		t.Errorf("Sections() = %s, want %s", got, want)
+	}
+
+	a, err := NewAssistant("Support").WithFirstMessage("old").Build()
+	if err != nil {
+		t.Fatalf("Build() error = %v", err)
+	}
+	if err := p.ApplyToAssistant(a, map[string]string{"Company": "Acme"}); err != nil {
+		t.Fatalf("ApplyToAssistant() error = %v", err)
+	}
+
+	if got := a.Model.Messages[0]; got.Role != "system" || got.Content != "You are a support agent for Acme." {
+		t.Errorf("system message = %+v", got)
+	}
+	if *a.FirstMessage != "Hi, this is Acme support." {
+		t.Errorf("FirstMessage = %q", *a.FirstMessage)
+	}
+	if *a.VoicemailMessage != "Please call Acme back." {
+		t.Errorf("VoicemailMessage = %q", *a.VoicemailMessage)
+	}
+	if a.EndCallMessage != nil {
+		t.Errorf("EndCallMessage = %q, want unchanged", *a.EndCallMessage)
+	}
+	msgs := a.AnalysisPlan.SummaryPlan.Messages
+	if len(msgs) != 2 || msgs[0].Content != "Summarize the support call in two sentences." || !strings.Contains(msgs[1].Content, "{{transcript}}") {
+		t.Errorf("summary messages = %+v", msgs)
+	}
+	if a.Metadata[MetadataPromptName] != "support" || a.Metadata[MetadataPromptVersion] != "1.2.0" || a.Metadata[MetadataPromptSHA256] != p.Header.SHA256 {
+		t.Errorf("Metadata = %v", a.Metadata)
+	}
+}
+
+func TestPrompt_PartialSections(t *testing.T) {
+	// Sections a partial defines belong to the partial, not the prompt
+	partials := map[string]string{"closing": `{{define "endCallMessage"}}Bye.{{end}}Thanks.`}
+	p, err := parsePromptTemplate("---\nname: support\n---\nHello. {{template \"closing\" .}}", partials)
+	if err != nil {
+		t.Fatalf("parsePromptTemplate() error = %v", err)
+	}
+	if got := p.Sections(); len(got) != 0 {
+		t.Errorf("Sections() = %v, want none", got)
+	}
+
+	_, err = parsePromptTemplate("---\nname: support\n---\nHello.", map[string]string{"summary": "Summarize."})
+	if err == nil || !strings.Contains(err.Error(), `partial "summary"`) {
+		t.Errorf("partial named after a section error = %v", err)
+	}
+}
+
+func TestBumpPromptVersion(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "intro.md")
+	content := "---\nname: intro\nowner: growth-team\nversion: 1.4.2\n---\nHello {{.Name}}.\n"
+	if err := os.WriteFile(file, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	header, err := BumpPromptVersion(file, BumpMinor)
+	if err != nil {
+		t.Fatalf("BumpPromptVersion() error = %v", err)
+	}
+	if header.Version != "1.5.0" {
+		t.Errorf("Version = %s, want 1.5.0", header.Version)
+	}
+
+	got, _ := os.ReadFile(file)
+	want := "---\nname: intro\nowner: growth-team\nversion: 1.5.0\nsha256: " + header.SHA256 + "\n---\nHello {{.Name}}.\n"
+	if string(got) != want {
+		t.Errorf("file =\n%s\nwant\n%s", got, want)
+	}
+	if err := VerifyPromptDir(dir); err != nil {
+		t.Errorf("VerifyPromptDir() after bump error = %v", err)
+	}
+
+	// Editing the body without bumping is caught by verification
+	os.WriteFile(file, []byte(strings.Replace(string(got), "Hello", "Hi", 1)), 0600)
+	os.WriteFile(filepath.Join(dir, "unstamped.md"), []byte("---\nname: other\n---\nHi"), 0600)
+
+	err = VerifyPromptDir(dir)
+	var problems PromptIntegrityErrors
+	if !errors.As(err, &problems) {
+		t.Fatalf("VerifyPromptDir() error = %v, want PromptIntegrityErrors", err)
+	}
+	if len(problems) != 3 {
+		t.Errorf("got %d problems, want mismatch, missing sha256 and missing version: %v", len(problems), problems)
+	}
+
+	if header, err = BumpPromptVersion(file, BumpPatch); err != nil || header.Version != "1.5.1" {
+		t.Fatalf("BumpPromptVersion() = %v, %v", header.Version, err)
+	}
+	if _, err := CreatePromptTemplate(file); err != nil {
+		t.Errorf("CreatePromptTemplate() after bump error = %v", err)
+	}
+
+	// Stamping a hash does not add an empty version
+	unversioned := filepath.Join(t.TempDir(), "plain.md")
+	os.WriteFile(unversioned, []byte("---\nname: plain\n---\nHello."), 0600)
+	if header, err := BumpPromptVersion(unversioned, BumpNone); err != nil || header.Version != "" || header.SHA256 == "" {
+		t.Errorf("BumpPromptVersion(BumpNone) = %+v, %v", header, err)
+	}
+	if got, _ := os.ReadFile(unversioned); strings.Contains(string(got), "version") {
+		t.Errorf("BumpNone wrote a version:\n%s", got)
+	}
+
+	// The hash covers nested partials, as verification expects
+	nested := t.TempDir()
+	os.MkdirAll(filepath.Join(nested, PartialsDir), 0700)
+	os.WriteFile(filepath.Join(nested, PartialsDir, "_sign.md"), []byte("Bye."), 0600)
+	os.MkdirAll(filepath.Join(nested, "old"), 0700)
+	os.WriteFile(filepath.Join(nested, "old", "_sign.md"), []byte("Farewell."), 0600)
+	file = filepath.Join(nested, "outro.md")
+	os.WriteFile(file, []byte("---\nname: outro\nversion: 1.0.0\n---\n{{template \"sign\" .}}"), 0600)
+	if _, err := BumpPromptVersion(file, BumpPatch); err != nil {
+		t.Fatalf("BumpPromptVersion() with nested partial error = %v", err)
+	}
+	if err := VerifyPromptDir(nested); err != nil {
+		t.Errorf("VerifyPromptDir() after bump with nested partial error = %v", err)
+	}
+}
+
+func TestPromptFrontMatter(t *testing.T) {
+	tests := map[string]string{
+		"yaml": "---\nname: intro\nversion: 1.0.0\n---\nHello --- there ---",
+		"crlf": "\uFEFF---\r\nname: intro\r\nversion: 1.0.0\r\n---\r\nHello --- there ---",
+		"toml": "+++\nname = \"intro\"\nversion = \"1.0.0\"\n+++\nHello --- there ---",
+		"json": "---json\n{\n  \"name\": \"intro\",\n  \"version\": \"1.0.0\"\n}\n---\nHello --- there ---",
+	}
+	for name, content := range tests {
+		prompt, err := parsePromptTemplate(content, nil)
+		if err != nil {
+			t.Errorf("%s: parsePromptTemplate() error = %v", name, err)
+			continue
+		}
+		if prompt.Header.Name != "intro" || prompt.Header.Version != "1.0.0" || prompt.RawContent != "Hello --- there ---" {
+			t.Errorf("%s: header = %+v, body = %q", name, prompt.Header, prompt.RawContent)
+		}
+	}
+
+	// JSON needs its own delimiter, so a body can start with an object
+	if fm, err := parseFrontMatter("{\"name\": \"intro\"}\nHello"); err != nil || fm.format != "" {
+		t.Errorf("parseFrontMatter() of body starting with { = %+v, %v", fm, err)
+	}
+
+	// Delimiters in the body are not a header
+	prompt, err := parsePromptTemplate("Intro\n---\nname: x\n---\nBody", nil)
+	if err != nil || prompt.Header.Name != "" || !strings.HasPrefix(prompt.RawContent, "Intro\n---") {
+		t.Errorf("body with --- = %+v, %v", prompt.Header, err)
+	}
+
+	errs := map[string]int{
+		"---\nname: intro\nHello":                                       1,
+		"-----\nname: intro\n-----\nHello":                              1,
+		"---\nname: intro\nversion: [1\n---\n":                          3,
+		"+++\nname = \"intro\"\nversion = 1 x\n+++\n":                   3,
+		"---json\n{\n  \"name\": \"intro\",\n  \"version\" 1\n}\n---\n": 4,
+	}
+	for content, line := range errs {
+		_, err := parsePromptTemplate(content, nil)
+		var fmErr *FrontMatterError
+		if !errors.As(err, &fmErr) || fmErr.Line != line {
+			t.Errorf("parsePromptTemplate(%q) error = %v, want FrontMatterError on line %d", content, err, line)
+		}
+	}
+}
+
+func TestBumpPromptVersion_Formats(t *testing.T) {
+	tests := map[string]string{
+		"toml.md": "+++\nname = \"intro\"\nversion = \"1.0.0\"\n\n[owner]\nteam = \"growth\"\n+++\nHello\n",
+		"json.md": "---json\n{\n  \"name\": \"intro\",\n  \"version\": \"1.0.0\",\n  \"owner\": {\"version\": \"2\"}\n}\n---\nHello\n",
+		"yaml.md": "---\n# Intro prompt\nname: intro\nversion: 1.0.0 # bumped by CI\nowner:\n  version: 2\n---\nHello\n",
+		"crlf.md": "\uFEFF---\r\nname: intro\r\nversion: 1.0.0\r\n---\r\nHello\r\n",
+	}
+	for name, content := range tests {
+		file := filepath.Join(t.TempDir(), name)
+		os.WriteFile(file, []byte(content), 0600)
+
+		header, err := BumpPromptVersion(file, BumpPatch)
+		if err != nil || header.Version != "1.0.1" {
+			t.Errorf("%s: BumpPromptVersion() = %v, %v", name, header.Version, err)
+			continue
+		}
+		prompt, err := CreatePromptTemplate(file)
+		if err != nil || prompt.Header.SHA256 != header.SHA256 {
+			t.Errorf("%s: CreatePromptTemplate() after bump = %+v, %v", name, prompt.Header, err)
+		}
+		if prompt.Header.Extra["owner"] == nil && name == "toml.md" {
+			t.Errorf("%s: table after the header keys was lost", name)
+		}
+		got, _ := os.ReadFile(file)
+		if name == "json.md" && (!strings.Contains(string(got), `"version": "1.0.1",`) || !strings.Contains(string(got), `{"version": "2"}`)) {
+			t.Errorf("%s: wrong version changed: %s", name, got)
+		}
+		if name == "yaml.md" && !strings.HasPrefix(string(got), "---\n# Intro prompt\nname: intro\nversion: 1.0.1 # bumped by CI\nowner:\n  version: 2\nsha256: ") {
+			t.Errorf("%s: comments or key order not preserved: %s", name, got)
+		}
+		if name == "crlf.md" && (!strings.HasPrefix(string(got), "\uFEFF---\r\n") || strings.Count(string(got), "\n") != strings.Count(string(got), "\r\n")) {
+			t.Errorf("%s: line endings or BOM not preserved: %q", name, got)
+		}
+	}
+}
+
+func TestLintPrompt(t *testing.T) {
+	content := `---
+name: intro
+---
+Hello {{customer.name}}, today is {{now}}.
+You are {{shout .Agent}}. Never say guaranteed returns.
+Reply with JSON like {"ok": true} or ` + "`{ partial }`" + ` and \{escaped\}.
+{{if .VIP}}Welcome back {{.Name}}}{{end}}
+{{template "missing" .}}`
+
+	diags := LintPrompt(content, LintOptions{
+		ForbiddenPhrases: []string{"Guaranteed Returns"},
+		MaxTokens:        10,
+	})
+
+	want := []string{
+		"4:7: error: {{customer.name}} is Vapi variable syntax",
+		"4:35: error: {{now}} is Vapi variable syntax",
+		"5:11: error: function \"shout\" not defined",
+		"5:37: error: forbidden phrase \"Guaranteed Returns\"",
+		"7:34: error: template contains single right curly brace",
+		"8:1: error: template includes undefined partial \"missing\"",
+	}
+	var got []string
+	for _, d := range diags {
+		if d.Rule != LintPromptLength {
+			got = append(got, d.String())
+		}
+	}
+	if len(got) != len(want) {
+		t.Fatalf("LintPrompt() =\n%s\nwant %d diagnostics", strings.Join(got, "\n"), len(want))
+	}
+	for i := range want {
+		if !strings.HasPrefix(got[i], want[i]) {
+			t.Errorf("diagnostic %d = %s, want %s...", i, got[i], want[i])
+		}
+	}
+	if len(diags) != len(want)+1 || diags[0].Rule != LintPromptLength || diags[0].Severity != LintWarning {
+		t.Errorf("expected a prompt-length warning first, got %v", diags)
+	}
+
+	diags = LintPrompt("---\nname: x\n---\nHi {{ .Name \n\nBye {{.Other}}", LintOptions{})
+	if len(diags) != 1 || diags[0].Rule != LintUnclosedAction || diags[0].Line != 4 || diags[0].Column != 4 {
+		t.Errorf("unclosed action diagnostics = %v", diags)
+	}
+	if err := LintPrompt(`{{"{{name}}"}} {{define "system"}}x{{end}}{{template "system" .}}`, LintOptions{}).Err(); err != nil {
+		t.Errorf("LintPrompt() on valid prompt error = %v", err)
+	}
+
+	// Loading still rejects single braces, but not in code or JSON examples
+	if _, err := parsePromptTemplate("---\nname: x\n---\nHi {name}", nil); err == nil || !strings.Contains(err.Error(), "line 4:4") {
+		t.Errorf("parsePromptTemplate() with single brace error = %v", err)
+	}
+	if _, err := parsePromptTemplate("Return `{id}` as {\"id\": 1}", nil); err != nil {
+		t.Errorf("parsePromptTemplate() with code span and JSON error = %v", err)
+	}
+}
+
+func TestPromptVariants(t *testing.T) {
+	control, _ := parsePromptTemplate("---\nname: intro\nversion: 1.0.0\n---\nHello {{.Name}}.", nil)
+	short, _ := parsePromptTemplate("---\nname: intro-short\nversion: 1.0.0\n---\nHi {{.Name}}.", nil)
+	variants, err := NewPromptVariants("intro",
+		PromptVariant{ID: "control", Weight: 75, Prompt: control},
+		PromptVariant{ID: "short", Weight: 25, Prompt: short},
+		PromptVariant{ID: "off", Weight: 0, Prompt: short},
+	)
+	if err != nil {
+		t.Fatalf("NewPromptVariants() error = %v", err)
+	}
+
+	counts := map[string]int{}
+	for i := 0; i < 4000; i++ {
+		key := fmt.Sprintf("+1555%07d", i)
+		v := variants.Pick(key)
+		if again := variants.Pick(key); again.ID != v.ID {
+			t.Fatalf("Pick(%s) = %s then %s, want a stable assignment", key, v.ID, again.ID)
+		}
+		counts[v.ID]++
+	}
+	if counts["off"] != 0 || counts["short"] < 800 || counts["short"] > 1200 {
+		t.Errorf("assignment counts = %v, want about 3000/1000/0", counts)
+	}
+
+	// Overrides without a model would replace the saved assistant's model
+	call, _ := NewOutboundCall("phone-1", Customer{Number: "+15551234567"}).WithAssistantID("asst-1").Build()
+	if _, err := variants.ApplyToCall(call, map[string]string{"Name": "Pat"}); err == nil || !strings.Contains(err.Error(), "model") {
+		t.Errorf("ApplyToCall() without a model: err = %v", err)
+	}
+	if call.AssistantOverrides != nil {
+		t.Errorf("AssistantOverrides = %+v after a failed ApplyToCall, want nil", call.AssistantOverrides)
+	}
+
+	call.AssistantOverrides = &Assistant{Model: &ModelConfig{Provider: "anthropic", Model: "claude-3-5-sonnet", Temperature: 0.2}}
+	variant, err := variants.ApplyToCall(call, map[string]string{"Name": "Pat"})
+	if err != nil {
+		t.Fatalf("ApplyToCall() error = %v", err)
+	}
+	if want := variants.Pick("+15551234567"); variant.ID != want.ID {
+		t.Errorf("ApplyToCall() picked %s, want %s", variant.ID, want.ID)
+	}
+	model := call.AssistantOverrides.Model
+	if got := model.Messages[0].Content; !strings.HasSuffix(got, "Pat.") {
+		t.Errorf("system prompt = %q", got)
+	}
+	if model.Provider != "anthropic" || model.Model != "claude-3-5-sonnet" || model.Temperature != 0.2 || model.MaxTokens != 0 {
+		t.Errorf("override model = %+v, want the caller's model unchanged", model)
+	}
+
+	// The variant survives the round trip through Vapi's end-of-call report
+	b, _ := json.Marshal(map[string]any{"type": MsgTypeEndOfCallReport, "call": call})
+	var report EndOfCallReport
+	if err := json.Unmarshal(b, &report); err != nil {
+		t.Fatal(err)
+	}
+	experiment, id, ok := report.PromptVariant()
+	if !ok || experiment != "intro" || id != variant.ID {
+		t.Errorf("PromptVariant() = %s, %s, %v; want intro, %s", experiment, id, ok, variant.ID)
+	}
+
+	if _, err := NewPromptVariants("intro", PromptVariant{ID: "a", Weight: 1}, PromptVariant{ID: "a", Weight: 1}); err == nil {
+		t.Error("NewPromptVariants() expected error for duplicate IDs")
+	}
+	if _, err := variants.ApplyToCall(&Call{}, nil); err == nil {
+		t.Error("ApplyToCall() expected error for call without customer number or ID")
+	}
+}
+
+func TestPromptLibrary_Locales(t *testing.T) {
+	if got := strings.Join(LocaleFallbacks("es_mx"), ","); got != "es-MX,es,en," {
+		t.Errorf("LocaleFallbacks(es_mx) = %s", got)
+	}
+
+	fsys := fstest.MapFS{
+		"en/intro.md": {Data: []byte("---\nname: intro\nversion: 2.0.0\nlocale: en\n---\nHello {{.Name}}.")},
+		"es/intro.md": {Data: []byte("---\nname: intro\nversion: 1.0.0\nlocale: es\n---\nHola {{.Name}}.")},
+		"fr/intro.md": {Data: []byte("---\nname: intro\nversion: 1.0.0\nlocale: fr-CA\n---\nBonjour {{.Name}}.")},
+	}
+	lib, err := LoadPromptLibraryFS(fsys)
+	if err != nil {
+		t.Fatalf("LoadPromptLibraryFS() error = %v", err)
+	}
+	if got := strings.Join(lib.Locales("intro"), ","); got != "en,es,fr-CA" {
+		t.Errorf("Locales() = %s", got)
+	}
+
+	for locale, want := range map[string]string{"es-MX": "Hola", "fr-CA": "Bonjour", "fr": "Hello", "": "Hello", "de": "Hello"} {
+		prompt, err := lib.LatestLocale("intro", locale)
+		if err != nil || !strings.HasPrefix(prompt.RawContent, want) {
+			t.Errorf("LatestLocale(%q) = %q, %v; want %s", locale, prompt.RawContent, err, want)
+		}
+	}
+	if _, err := lib.GetLocale("intro", "2.0.0", "es"); err != nil {
+		t.Errorf("GetLocale() should fall back to en: %v", err)
+	}
+
+	// Without a locale asked for, a library with no default-locale prompts
+	// still finds them
+	spanish, err := LoadPromptLibraryFS(fstest.MapFS{"es/intro.md": fsys["es/intro.md"]})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if prompt, err := spanish.Latest("intro"); err != nil || !strings.HasPrefix(prompt.RawContent, "Hola") {
+		t.Errorf("Latest() in an es-only library = %q, %v", prompt.RawContent, err)
+	}
+	if _, err := spanish.Get("intro", "1.0.0"); err != nil {
+		t.Errorf("Get() in an es-only library: %v", err)
+	}
+	if _, err := spanish.LatestLocale("intro", "fr"); err == nil {
+		t.Error("LatestLocale(fr) in an es-only library succeeded")
+	}
+
+	spanishVoice := ElevenLabsVoiceConfig{Provider: "11labs", VoiceID: "es-voice", Model: "eleven_multilingual_v2"}
+	profiles := LocaleProfiles{
+		"es":    {Transcriber: &TranscriberConfig{Provider: "deepgram", Model: "nova-2", Language: "es"}},
+		"es-mx": {Voice: &spanishVoice},
+	}
+	a, err := NewAssistant("Alex").
+		WithLocale("es-MX", profiles).
+		WithLibraryPrompt(lib, "intro", map[string]string{"Name": "Pat"}).
+		Build()
+	if err != nil {
+		t.Fatalf("Build() error = %v", err)
+	}
+	if a.Voice.VoiceID != "es-voice" || a.Transcriber.Language != "es" || a.Model.Messages[0].Content != "Hola Pat." {
+		t.Errorf("assistant voice = %s, language = %s, prompt = %q", a.Voice.VoiceID, a.Transcriber.Language, a.Model.Messages[0].Content)
+	}
+	if a.Metadata[MetadataPromptLocale] != "es" {
+		t.Errorf("Metadata = %v, want promptLocale es", a.Metadata)
+	}
+
+	// Without a profile the default transcriber switches language
+	a, _ = NewAssistant("Alex").WithLocale("fr-CA", nil).Build()
+	if a.Transcriber.Language != "fr" || a.Voice.VoiceID != DefaultElevenLabsVoiceConfig.VoiceID {
+		t.Errorf("fr-CA transcriber = %+v", a.Transcriber)
+	}
+	if _, err := NewAssistant("Alex").WithLibraryPrompt(lib, "missing", nil).Build(); err == nil {
+		t.Error("Build() expected error for missing library prompt")
+	}
+}
@@ -0,0 +1,328 @@
+package vapi
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"sort"
+	"strings"
+	"text/template/parse"
+)
+
+// PromptInput declares a variable a prompt expects in its data. Inputs are
+// listed in the prompt header:
+//
+//	inputs:
+//	  - name: Customer
+//	    type: map
+//	    required: true
+//	  - name: Count
+//	    type: integer
+//
+// Type is one of string, number, integer, boolean, list or map; an empty
+// type accepts any value.
+type PromptInput struct {
+	Name        string `yaml:"name"`
+	Type        string `yaml:"type,omitempty"`
+	Required    bool   `yaml:"required,omitempty"`
+	Description string `yaml:"description,omitempty"`
+}
+
+// VariableReport is the result of checking data against the variables a
+// template uses.
+type VariableReport struct {
+	// Missing variables are required or referenced but not provided
+	Missing []string
+	// Unused variables are provided but never referenced
+	Unused []string
+	// Invalid variables are provided with the wrong type
+	Invalid []string
+}
+
+// Err returns an error describing missing and invalid variables. Unused
+// variables are not an error.
+func (r VariableReport) Err() error {
+	var parts []string
+	if len(r.Missing) > 0 {
+		parts = append(parts, "missing variables: "+strings.Join(r.Missing, ", "))
+	}
+	if len(r.Invalid) > 0 {
+		parts = append(parts, "invalid variables: "+strings.Join(r.Invalid, ", "))
+	}
+	if len(parts) == 0 {
+		return nil
+	}
+	return fmt.Errorf("%s", strings.Join(parts, "; "))
+}
+
+// Variables returns every variable the template references, as dotted
+// paths from the top-level data (e.g. "Customer.Name"), sorted. Fields used
+// inside range and with blocks are relative to the block and are reported
+// through the block's own pipeline instead.
+func (p Prompt) Variables() []string {
+	if p.Template == nil || p.Template.Tree == nil {
+		return nil
+	}
+
+	seen := map[string]bool{}
+	visited := map[string]bool{}
+	var walk func(node parse.Node, atRoot bool)
+	walk = func(node parse.Node, atRoot bool) {
+		switch n := node.(type) {
+		case *parse.ListNode:
+			if n == nil {
+				return
+			}
+			for _, c := range n.Nodes {
+				walk(c, atRoot)
+			}
+		case *parse.ActionNode:
+			walk(n.Pipe, atRoot)
+		case *parse.PipeNode:
+			if n == nil {
+				return
+			}
+			for _, cmd := range n.Cmds {
+				walk(cmd, atRoot)
+			}
+		case *parse.CommandNode:
+			for _, arg := range n.Args {
+				walk(arg, atRoot)
+			}
+		case *parse.ChainNode:
+			walk(n.Node, atRoot)
+		case *parse.FieldNode:
+			if atRoot {
+				seen[strings.Join(n.Ident, ".")] = true
+			}
+		case *parse.VariableNode:
+			if len(n.Ident) > 1 && n.Ident[0] == "$" {
+				seen[strings.Join(n.Ident[1:], ".")] = true
+			}
+		case *parse.IfNode:
+			walk(n.Pipe, atRoot)
+			walk(n.List, atRoot)
+			walk(n.ElseList, atRoot)
+		case *parse.RangeNode:
+			walk(n.Pipe, atRoot)
+			walk(n.List, false)
+			walk(n.ElseList, atRoot)
+		case *parse.WithNode:
+			walk(n.Pipe, atRoot)
+			walk(n.List, false)
+			walk(n.ElseList, atRoot)
+		case *parse.TemplateNode:
+			walk(n.Pipe, atRoot)
+			if visited[n.Name] {
+				return
+			}
+			// Only follow the template when it is called with the same dot
+			passesDot := n.Pipe != nil && len(n.Pipe.Cmds) == 1 && len(n.Pipe.Cmds[0].Args) == 1
+			if passesDot {
+				_, passesDot = n.Pipe.Cmds[0].Args[0].(*parse.DotNode)
+			}
+			if t := p.Template.Lookup(n.Name); t != nil && t.Tree != nil && passesDot && atRoot {
+				visited[n.Name] = true
+				walk(t.Tree.Root, true)
+			}
+		}
+	}
+	walk(p.Template.Tree.Root, true)
+
+	vars := make([]string, 0, len(seen))
+	for v := range seen {
+		vars = append(vars, v)
+	}
+	sort.Strings(vars)
+	return vars
+}
+
+// CheckData compares data with the variables the template references and
+// the inputs declared in the header. data is usually a map with string keys
+// or a struct, whose exported field names are the variable names.
+func (p Prompt) CheckData(data any) VariableReport {
+	var report VariableReport
+	provided := templateDataFields(data)
+
+	declared := map[string]PromptInput{}
+	for _, in := range p.Header.Inputs {
+		declared[in.Name] = in
+	}
+
+	referenced := map[string]bool{}
+	for _, v := range p.Variables() {
+		referenced[strings.Split(v, ".")[0]] = true
+	}
+
+	missing := map[string]bool{}
+	for name := range referenced {
+		if in, ok := declared[name]; ok && !in.Required {
+			continue
+		}
+		if _, ok := provided[name]; !ok {
+			missing[name] = true
+		}
+	}
+	for _, in := range p.Header.Inputs {
+		value, ok := provided[in.Name]
+		if !ok {
+			if in.Required {
+				missing[in.Name] = true
+			}
+			continue
+		}
+		if !matchesInputType(in.Type, value) {
+			report.Invalid = append(report.Invalid, fmt.Sprintf("%s (want %s, got %T)", in.Name, in.Type, value))
+		}
+	}
+	for name := range missing {
+		report.Missing = append(report.Missing, name)
+	}
+	for name := range provided {
+		if !referenced[name] {
+			report.Unused = append(report.Unused, name)
+		}
+	}
+
+	sort.Strings(report.Missing)
+	sort.Strings(report.Unused)
+	sort.Strings(report.Invalid)
+	return report
+}
+
+// templateDataFields returns the top-level values a template sees in data
+func templateDataFields(data any) map[string]any {
+	fields := map[string]any{}
+	rv := reflect.ValueOf(data)
+	for rv.Kind() == reflect.Ptr || rv.Kind() == reflect.Interface {
+		if rv.IsNil() {
+			return fields
+		}
+		rv = rv.Elem()
+	}
+
+	switch rv.Kind() {
+	case reflect.Map:
+		if rv.Type().Key().Kind() != reflect.String {
+			return fields
+		}
+		for _, k := range rv.MapKeys() {
+			fields[k.String()] = rv.MapIndex(k).Interface()
+		}
+	case reflect.Struct:
+		t := rv.Type()
+		for i := 0; i < t.NumField(); i++ {
+			if t.Field(i).IsExported() {
+				fields[t.Field(i).Name] = rv.Field(i).Interface()
+			}
+		}
+	}
+	return fields
+}
+
+// matchesInputType reports whether value fits the declared input type
+func matchesInputType(typ string, value any) bool {
+	rv := reflect.ValueOf(value)
+	for rv.Kind() == reflect.Ptr || rv.Kind() == reflect.Interface {
+		if rv.IsNil() {
+			return true
+		}
+		rv = rv.Elem()
+	}
+
+	switch typ {
+	case "", "any":
+		return true
+	case "string":
+		return rv.Kind() == reflect.String
+	case "boolean":
+		return rv.Kind() == reflect.Bool
+	case "list":
+		return rv.Kind() == reflect.Slice || rv.Kind() == reflect.Array
+	case "map":
+		return rv.Kind() == reflect.Map || rv.Kind() == reflect.Struct
+	case "number", "integer":
+		switch rv.Kind() {
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+			reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+			return true
+		case reflect.Float32, reflect.Float64:
+			return typ == "number" || rv.Float() == math.Trunc(rv.Float())
+		}
+	}
+	return false
+}
+
+// vapiBuiltinVariables are filled in by Vapi at call time and never need a
+// value in VariableValues.
+var vapiBuiltinVariables = map[string]bool{
+	"now":      true,
+	"date":     true,
+	"time":     true,
+	"month":    true,
+	"day":      true,
+	"year":     true,
+	"customer": true,
+}
+
+// VapiVariables returns the names of the Vapi {{variable}} placeholders in s,
+// in order of first appearance.
+func VapiVariables(s string) []string {
+	var names []string
+	seen := map[string]bool{}
+	for _, m := range vapiVariablePattern.FindAllStringSubmatch(s, -1) {
+		if !seen[m[1]] {
+			seen[m[1]] = true
+			names = append(names, m[1])
+		}
+	}
+	return names
+}
+
+// CheckVariableValues compares VariableValues with the {{variable}}
+// placeholders in the first message, end-call and voicemail messages and the
+// model messages. Vapi's built-in variables such as {{now}} and
+// {{customer.number}} never count as missing.
+func (a *Assistant) CheckVariableValues() VariableReport {
+	var texts []string
+	for _, s := range []*string{a.FirstMessage, a.EndCallMessage, a.VoicemailMessage} {
+		if s != nil {
+			texts = append(texts, *s)
+		}
+	}
+	if a.Model != nil {
+		for _, m := range a.Model.Messages {
+			texts = append(texts, m.Content)
+		}
+	}
+
+	var report VariableReport
+	referenced := map[string]bool{}
+	for _, text := range texts {
+		for _, name := range VapiVariables(text) {
+			root := strings.Split(name, ".")[0]
+			referenced[root] = true
+			if _, ok := lookupVariable(a.VariableValues, name); !ok && !vapiBuiltinVariables[root] && !contains(report.Missing, name) {
+				report.Missing = append(report.Missing, name)
+			}
+		}
+	}
+	for name := range a.VariableValues {
+		if !referenced[name] {
+			report.Unused = append(report.Unused, name)
+		}
+	}
+
+	sort.Strings(report.Missing)
+	sort.Strings(report.Unused)
+	return report
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,168 @@
+package vapi
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+)
+
+// Keys ApplyToCall records the variant under, in both the assistant's
+// Metadata and VariableValues. The variable values come back on every call
+// object and the end-of-call report, so analysis can be grouped by variant.
+const (
+	MetadataPromptExperiment = "promptExperiment"
+	MetadataPromptVariant    = "promptVariant"
+)
+
+// PromptVariant is one arm of a prompt experiment. Weight is relative to the
+// other variants; a variant with weight 0 is never picked.
+type PromptVariant struct {
+	ID     string
+	Weight int
+	Prompt Prompt
+}
+
+// PromptVariants is a set of weighted variants of one prompt, for A/B
+// testing prompts on outbound campaigns:
+//
+//	a, _ := lib.Get("intro", "1.0.0")
+//	b, _ := lib.Get("intro-short", "1.0.0")
+//	variants, err := vapi.NewPromptVariants("intro",
+//		vapi.PromptVariant{ID: "control", Weight: 80, Prompt: a},
+//		vapi.PromptVariant{ID: "short", Weight: 20, Prompt: b},
+//	)
+//
+// Assignment is deterministic: the same customer always gets the same
+// variant of the same experiment.
+type PromptVariants struct {
+	Name     string
+	Variants []PromptVariant
+
+	total int
+}
+
+// NewPromptVariants checks the variants and returns the experiment. Variant
+// IDs must be unique and not empty, and at least one weight must be positive.
+func NewPromptVariants(name string, variants ...PromptVariant) (*PromptVariants, error) {
+	if name == "" {
+		return nil, fmt.Errorf("prompt variants need a name")
+	}
+	pv := &PromptVariants{Name: name, Variants: variants}
+	seen := map[string]bool{}
+	for _, v := range variants {
+		switch {
+		case v.ID == "":
+			return nil, fmt.Errorf("prompt variants %q: variant without an ID", name)
+		case seen[v.ID]:
+			return nil, fmt.Errorf("prompt variants %q: duplicate variant %q", name, v.ID)
+		case v.Weight < 0:
+			return nil, fmt.Errorf("prompt variants %q: variant %q has negative weight %d", name, v.ID, v.Weight)
+		}
+		seen[v.ID] = true
+		pv.total += v.Weight
+	}
+	if pv.total == 0 {
+		return nil, fmt.Errorf("prompt variants %q: no variant has a positive weight", name)
+	}
+	return pv, nil
+}
+
+// Pick returns the variant for key. The key is hashed together with the
+// experiment name, so a customer's variant in one experiment says nothing
+// about their variant in another.
+func (pv *PromptVariants) Pick(key string) PromptVariant {
+	sum := sha256.Sum256([]byte(pv.Name + "\x00" + key))
+	n := int(binary.BigEndian.Uint64(sum[:8]) % uint64(pv.total))
+	for _, v := range pv.Variants {
+		if n < v.Weight {
+			return v
+		}
+		n -= v.Weight
+	}
+	// unreachable: n is always less than the total weight
+	return pv.Variants[len(pv.Variants)-1]
+}
+
+// PickForCall picks the variant for a call, keyed on the customer's number
+// or, when the call has none, on the call ID.
+func (pv *PromptVariants) PickForCall(call *Call) (PromptVariant, error) {
+	switch {
+	case call.Customer != nil && call.Customer.Number != "":
+		return pv.Pick(call.Customer.Number), nil
+	case call.ID != nil && *call.ID != "":
+		return pv.Pick(*call.ID), nil
+	}
+	return PromptVariant{}, fmt.Errorf("prompt variants %q: call has no customer number or ID to pick a variant with", pv.Name)
+}
+
+// ApplyToCall picks a variant for the call, renders its prompt into the
+// call's inline assistant (or its AssistantOverrides when the call uses an
+// assistant ID) and records the experiment name and variant ID there. A
+// system prompt needs a model to go into: for a call using an assistant ID,
+// set AssistantOverrides.Model to the saved assistant's model first, since
+// Vapi replaces the model as a whole.
+func (pv *PromptVariants) ApplyToCall(call *Call, data any) (PromptVariant, error) {
+	variant, err := pv.PickForCall(call)
+	if err != nil {
+		return variant, err
+	}
+
+	target := call.Assistant
+	if target == nil {
+		target = call.AssistantOverrides
+		if target == nil {
+			target = &Assistant{}
+		}
+	}
+	if err := variant.Prompt.ApplyToAssistant(target, data); err != nil {
+		return variant, fmt.Errorf("prompt variant %q: %w", variant.ID, err)
+	}
+	if call.Assistant == nil {
+		call.AssistantOverrides = target
+	}
+
+	if target.VariableValues == nil {
+		target.VariableValues = map[string]any{}
+	}
+	target.Metadata[MetadataPromptExperiment] = pv.Name
+	target.Metadata[MetadataPromptVariant] = variant.ID
+	target.VariableValues[MetadataPromptExperiment] = pv.Name
+	target.VariableValues[MetadataPromptVariant] = variant.ID
+	return variant, nil
+}
+
+// PromptVariant returns the experiment name and variant ID ApplyToCall
+// recorded on the call, if any.
+func (c *Call) PromptVariant() (experiment, variant string, ok bool) {
+	if c == nil {
+		return "", "", false
+	}
+	return promptVariantOf(c.AssistantOverrides, c.Assistant)
+}
+
+// PromptVariant returns the experiment name and variant ID the call in the
+// report ran with, so reports can be grouped by variant.
+func (r *EndOfCallReport) PromptVariant() (experiment, variant string, ok bool) {
+	if experiment, variant, ok = r.Call.PromptVariant(); ok {
+		return experiment, variant, true
+	}
+	return promptVariantOf(r.Assistant)
+}
+
+// promptVariantOf looks for a recorded variant in the variable values, then
+// the metadata, of each assistant in turn.
+func promptVariantOf(assistants ...*Assistant) (experiment, variant string, ok bool) {
+	for _, a := range assistants {
+		if a == nil {
+			continue
+		}
+		for _, values := range []map[string]any{a.VariableValues, a.Metadata} {
+			v, _ := values[MetadataPromptVariant].(string)
+			if v != "" {
+				e, _ := values[MetadataPromptExperiment].(string)
+				return e, v, true
+			}
+		}
+	}
+	return "", "", false
+}
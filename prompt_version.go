@@ -0,0 +1,233 @@
+package vapi
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// VersionBump selects which part of a semantic version to increment
+type VersionBump int
+
+const (
+	// BumpNone keeps the version and only rewrites the hash. Use it to stamp
+	// a hash on a prompt that does not have one yet. A prompt without a
+	// version is left without one.
+	BumpNone VersionBump = iota
+	BumpPatch
+	BumpMinor
+	BumpMajor
+)
+
+// bumpVersion increments a "major.minor.patch" version. A leading "v" is
+// kept, pre-release and build suffixes are dropped and an empty version
+// counts as 0.0.0.
+func bumpVersion(version string, bump VersionBump) (string, error) {
+	if bump == BumpNone {
+		return version, nil
+	}
+
+	prefix := ""
+	if strings.HasPrefix(version, "v") {
+		prefix = "v"
+	}
+	core := strings.TrimPrefix(version, prefix)
+	if i := strings.IndexAny(core, "-+"); i >= 0 {
+		core = core[:i]
+	}
+
+	var parts [3]int
+	if core != "" {
+		fields := strings.Split(core, ".")
+		if len(fields) > 3 {
+			return "", fmt.Errorf("invalid version %q", version)
+		}
+		for i, f := range fields {
+			n, err := strconv.Atoi(f)
+			if err != nil || n < 0 {
+				return "", fmt.Errorf("invalid version %q", version)
+			}
+			parts[i] = n
+		}
+	}
+
+	switch bump {
+	case BumpMajor:
+		parts = [3]int{parts[0] + 1, 0, 0}
+	case BumpMinor:
+		parts = [3]int{parts[0], parts[1] + 1, 0}
+	case BumpPatch:
+		parts[2]++
+	default:
+		return "", fmt.Errorf("unknown version bump %d", bump)
+	}
+	return fmt.Sprintf("%s%d.%d.%d", prefix, parts[0], parts[1], parts[2]), nil
+}
+
+// BumpPromptVersion increments the version of the prompt file, recomputes
+// its SHA256 (including the partials it uses, resolved as by
+// CreatePromptTemplate) and writes both back to the file. The order of the
+// header keys, any extra keys and the body are left as they are, as are the
+// header format (YAML, TOML or JSON), line endings and byte order mark. It
+// returns the updated header.
+func BumpPromptVersion(filePath string, bump VersionBump) (PromptHeader, error) {
+	info, err := os.Stat(filePath)
+	if err != nil {
+		return PromptHeader{}, err
+	}
+	content, err := os.ReadFile(filePath)
+	if err != nil {
+		return PromptHeader{}, err
+	}
+	partials, err := loadPartials(os.DirFS(filepath.Dir(filePath)))
+	if err != nil {
+		return PromptHeader{}, err
+	}
+
+	prompt, _, err := compilePrompt(string(content), partials)
+	if err != nil {
+		return PromptHeader{}, err
+	}
+	version, err := bumpVersion(prompt.Header.Version, bump)
+	if err != nil {
+		return PromptHeader{}, err
+	}
+
+	fm, err := parseFrontMatter(string(content))
+	if err != nil {
+		return PromptHeader{}, err
+	}
+	if version != "" {
+		if err := fm.setField("version", version); err != nil {
+			return PromptHeader{}, err
+		}
+	}
+
+	// The hash covers only the body, so it can be computed before it is set
+	prompt, sha, err := compilePrompt(fm.String(), partials)
+	if err != nil {
+		return PromptHeader{}, err
+	}
+	if err := fm.setField("sha256", sha); err != nil {
+		return PromptHeader{}, err
+	}
+
+	// Write the file back with the line endings and BOM it was read with
+	updated := fm.String()
+	if strings.Contains(string(content), "\r\n") {
+		updated = strings.ReplaceAll(updated, "\n", "\r\n")
+	}
+	if strings.HasPrefix(string(content), utf8BOM) {
+		updated = utf8BOM + updated
+	}
+
+	if err := os.WriteFile(filePath, []byte(updated), info.Mode().Perm()); err != nil {
+		return PromptHeader{}, err
+	}
+
+	prompt.Header.SHA256 = sha
+	return prompt.Header, nil
+}
+
+// PromptIntegrityError describes one problem found by VerifyPromptLibrary
+type PromptIntegrityError struct {
+	File    string
+	Problem string
+}
+
+func (e PromptIntegrityError) Error() string {
+	return fmt.Sprintf("%s: %s", e.File, e.Problem)
+}
+
+// PromptIntegrityErrors is every problem found by VerifyPromptLibrary
+type PromptIntegrityErrors []PromptIntegrityError
+
+func (e PromptIntegrityErrors) Error() string {
+	msgs := make([]string, len(e))
+	for i, err := range e {
+		msgs[i] = err.Error()
+	}
+	return fmt.Sprintf("%d prompt integrity error(s):\n%s", len(e), strings.Join(msgs, "\n"))
+}
+
+// VerifyPromptDir runs VerifyPromptLibrary on a directory
+func VerifyPromptDir(dir string) error {
+	return VerifyPromptLibrary(os.DirFS(dir))
+}
+
+// VerifyPromptLibrary checks every prompt in fsys the way a CI job would:
+// each prompt must parse, have a version and a SHA256 that matches its
+// content and partials, and no two prompts may share a name, version and
+// locale. Unlike LoadPromptLibraryFS it does not stop at the first problem;
+// all of them are returned as PromptIntegrityErrors.
+func VerifyPromptLibrary(fsys fs.FS) error {
+	var problems PromptIntegrityErrors
+	report := func(file, format string, args ...any) {
+		problems = append(problems, PromptIntegrityError{File: file, Problem: fmt.Sprintf(format, args...)})
+	}
+
+	partials, err := loadPartials(fsys)
+	if err != nil {
+		return err
+	}
+
+	seen := map[string]string{}
+	err = fs.WalkDir(fsys, ".", func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if p != "." && strings.HasPrefix(d.Name(), ".") {
+			if d.IsDir() {
+				return fs.SkipDir
+			}
+			return nil
+		}
+		if d.IsDir() || isPartialFile(p) || !isPromptFile(p) {
+			return nil
+		}
+
+		content, err := fs.ReadFile(fsys, p)
+		if err != nil {
+			return err
+		}
+		prompt, sha, err := compilePrompt(string(content), partials)
+		if err != nil {
+			report(p, "%v", err)
+			return nil
+		}
+
+		header := prompt.Header
+		if header.Name == "" {
+			base := path.Base(p)
+			header.Name = strings.TrimSuffix(base, path.Ext(base))
+		}
+		switch {
+		case header.SHA256 == "":
+			report(p, "missing sha256, stamp it with BumpPromptVersion")
+		case header.SHA256 != sha:
+			report(p, "sha256 mismatch: header has %s, content hashes to %s; bump the version", header.SHA256, sha)
+		}
+		if header.Version == "" {
+			report(p, "missing version")
+		}
+
+		key := header.Name + "@" + header.Version + "@" + NormalizeLocale(header.Locale)
+		if other, ok := seen[key]; ok {
+			report(p, "duplicate prompt %q version %q, also in %s", header.Name, header.Version, other)
+		}
+		seen[key] = p
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	if len(problems) > 0 {
+		return problems
+	}
+	return nil
+}
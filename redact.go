@@ -0,0 +1,445 @@
+package vapi
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+
+	openai "github.com/sashabaranov/go-openai"
+)
+
+// Kinds of personal data the Redactor detects
+const (
+	PIIPhone = "PHONE"
+	PIIEmail = "EMAIL"
+	PIICard  = "CARD"
+	PIISSN   = "SSN"
+	PIIName  = "NAME"
+	PIISIP   = "SIP"
+)
+
+// RedactPattern is an extra expression for the Redactor to mask, such as an
+// account number format. Matches are replaced with "[Kind]".
+type RedactPattern struct {
+	Kind    string
+	Pattern *regexp.Regexp
+}
+
+// Built-in detectors, in order of precedence. Cards are checked before
+// phone numbers since a card number contains digit runs that look like one.
+var piiPatterns = []RedactPattern{
+	{PIIEmail, regexp.MustCompile(`[A-Za-z0-9._%+-]+@[A-Za-z0-9-]+(?:\.[A-Za-z0-9-]+)*\.[A-Za-z]{2,}`)},
+	{PIICard, regexp.MustCompile(`\b\d(?:[ -]?\d){12,18}\b`)},
+	{PIISSN, regexp.MustCompile(`\b(?:\d{3}-\d{2}-\d{4}|\d{3} \d{2} \d{4}|\d{9})\b`)},
+	{PIIPhone, regexp.MustCompile(`(?:\+\d{1,3}[ .-]?)?(?:\(\d{3}\)|\b\d{3})[ .-]?\d{3}[ .-]?\d{4}\b|\+[1-9]\d{7,14}\b`)},
+}
+
+// redactTokenPattern matches tokens of any kind, including custom kinds
+// such as "ACCOUNT-ID" or "ACCOUNT NUMBER"
+var redactTokenPattern = regexp.MustCompile(`\[[^\[\]:\n]+:[0-9a-f]{12}\]`)
+
+// Redactor masks phone numbers, emails, card numbers, SSNs and custom
+// patterns in call data before it is stored. Every text field is redacted
+// the same way, so a transcript and its messages still line up:
+//
+//	r := vapi.NewRedactor()
+//	r.RedactReport(report) // "call me at 555-123-4567" -> "call me at [PHONE]"
+//
+// A tokenizing redactor replaces each value with a token keyed by HMAC
+// instead, e.g. "[PHONE:1f3a9c0b7d2e]". The same value always gets the same
+// token, so calls can still be joined on it, and Restore maps tokens back to
+// the values they replaced.
+type Redactor struct {
+	patterns []RedactPattern
+	key      []byte
+
+	mu     sync.Mutex
+	tokens map[string]string
+}
+
+// NewRedactor returns a redactor masking the built-in kinds of personal
+// data and patterns. Custom patterns take precedence over the built-ins.
+func NewRedactor(patterns ...RedactPattern) *Redactor {
+	all := make([]RedactPattern, 0, len(patterns)+len(piiPatterns))
+	for _, p := range patterns {
+		p.Kind = strings.ToUpper(p.Kind)
+		all = append(all, p)
+	}
+	return &Redactor{patterns: append(all, piiPatterns...)}
+}
+
+// NewTokenizingRedactor returns a redactor replacing personal data with
+// tokens keyed by key
+func NewTokenizingRedactor(key []byte, patterns ...RedactPattern) *Redactor {
+	r := NewRedactor(patterns...)
+	r.key = key
+	r.tokens = map[string]string{}
+	return r
+}
+
+// Tokens returns the values replaced so far by token, for storing somewhere
+// safer than the redacted data
+func (r *Redactor) Tokens() map[string]string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	tokens := make(map[string]string, len(r.tokens))
+	for t, v := range r.tokens {
+		tokens[t] = v
+	}
+	return tokens
+}
+
+// LoadTokens adds tokens saved from Tokens, so Restore can reverse data
+// redacted by another process
+func (r *Redactor) LoadTokens(tokens map[string]string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.tokens == nil {
+		r.tokens = map[string]string{}
+	}
+	for t, v := range tokens {
+		r.tokens[t] = v
+	}
+}
+
+// Restore replaces the tokens in s with the values they stand for. Tokens
+// the redactor does not know are left as they are.
+func (r *Redactor) Restore(s string) string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return redactTokenPattern.ReplaceAllStringFunc(s, func(token string) string {
+		if v, ok := r.tokens[token]; ok {
+			return v
+		}
+		return token
+	})
+}
+
+// String redacts s
+func (r *Redactor) String(s string) string {
+	if s == "" {
+		return s
+	}
+	type span struct {
+		start, end int
+		kind       string
+	}
+	var spans []span
+	for _, p := range r.patterns {
+	matches:
+		for _, m := range p.Pattern.FindAllStringIndex(s, -1) {
+			if p.Kind == PIICard && !luhnValid(s[m[0]:m[1]]) {
+				continue
+			}
+			for _, sp := range spans {
+				if m[0] < sp.end && sp.start < m[1] {
+					continue matches
+				}
+			}
+			spans = append(spans, span{m[0], m[1], p.Kind})
+		}
+	}
+	if len(spans) == 0 {
+		return s
+	}
+	sort.Slice(spans, func(i, j int) bool { return spans[i].start < spans[j].start })
+
+	var b strings.Builder
+	last := 0
+	for _, sp := range spans {
+		b.WriteString(s[last:sp.start])
+		b.WriteString(r.replacement(sp.kind, s[sp.start:sp.end]))
+		last = sp.end
+	}
+	b.WriteString(s[last:])
+	return b.String()
+}
+
+// replacement is the mask or token for value
+func (r *Redactor) replacement(kind, value string) string {
+	if r.key == nil {
+		return "[" + kind + "]"
+	}
+	mac := hmac.New(sha256.New, r.key)
+	mac.Write([]byte(kind + "\x00" + normalizePII(kind, value)))
+	token := "[" + kind + ":" + hex.EncodeToString(mac.Sum(nil))[:12] + "]"
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.tokens[token]; !ok {
+		r.tokens[token] = value
+	}
+	return token
+}
+
+// normalizePII reduces the ways of writing a value to one, so "(555)
+// 123-4567" and "555.123.4567" get the same token
+func normalizePII(kind, value string) string {
+	switch kind {
+	case PIIEmail:
+		return strings.ToLower(value)
+	case PIIPhone, PIICard, PIISSN:
+		digits := onlyDigits(value)
+		if kind == PIIPhone && len(digits) == 11 && digits[0] == '1' {
+			digits = digits[1:]
+		}
+		return digits
+	}
+	return value
+}
+
+func onlyDigits(s string) string {
+	var b strings.Builder
+	for _, c := range s {
+		if c >= '0' && c <= '9' {
+			b.WriteRune(c)
+		}
+	}
+	return b.String()
+}
+
+// luhnValid reports whether the digits of s pass the Luhn checksum used by
+// card numbers
+func luhnValid(s string) bool {
+	digits := onlyDigits(s)
+	if len(digits) < 13 || len(digits) > 19 {
+		return false
+	}
+	sum := 0
+	for i := len(digits) - 1; i >= 0; i-- {
+		d := int(digits[i] - '0')
+		if (len(digits)-i)%2 == 0 {
+			d *= 2
+			if d > 9 {
+				d -= 9
+			}
+		}
+		sum += d
+	}
+	return sum%10 == 0
+}
+
+// RedactCall redacts the call's messages, artifact, analysis, customer and
+// assistants
+func (r *Redactor) RedactCall(c *Call) {
+	if c == nil {
+		return
+	}
+	r.redactMessages(c.Messages)
+	r.RedactArtifact(c.Artifact)
+	r.RedactAnalysis(c.Analysis)
+	r.redactCustomer(c.Customer)
+	r.redactAssistant(c.Assistant)
+	r.redactAssistant(c.AssistantOverrides)
+	r.redactExtra(reflect.ValueOf(c))
+}
+
+// RedactReport redacts an end-of-call report, including its call
+func (r *Redactor) RedactReport(report *EndOfCallReport) {
+	if report == nil {
+		return
+	}
+	r.RedactArtifact(report.Artifact)
+	r.RedactAnalysis(report.Analysis)
+	r.redactString(report.Summary)
+	r.redactString(report.Transcript)
+	r.redactMessages(report.Messages)
+	r.redactCustomer(report.Customer)
+	r.redactAssistant(report.Assistant)
+	r.RedactCall(report.Call)
+	r.redactExtra(reflect.ValueOf(report))
+}
+
+// RedactConversationUpdate redacts a conversation update, including its call
+func (r *Redactor) RedactConversationUpdate(u *ConversationUpdate) {
+	if u == nil {
+		return
+	}
+	for i := range u.OpenAIMessages {
+		u.OpenAIMessages[i].Content = r.String(u.OpenAIMessages[i].Content)
+	}
+	r.redactMessages(u.Messages)
+	r.redactCustomer(u.Customer)
+	r.RedactCall(u.Call)
+	r.redactExtra(reflect.ValueOf(u))
+}
+
+// RedactArtifact redacts the transcript and both message lists. Recording
+// URLs are left alone.
+func (r *Redactor) RedactArtifact(a *Artifact) {
+	if a == nil {
+		return
+	}
+	a.Transcript = r.String(a.Transcript)
+	r.redactMessages(a.Messages)
+	r.redactOpenAIMessages(a.MessagesOpenAIFormatted)
+	r.redactExtra(reflect.ValueOf(a))
+}
+
+// RedactAnalysis redacts the summary, every string in the structured data
+// and a free-text success evaluation
+func (r *Redactor) RedactAnalysis(a *Analysis) {
+	if a == nil {
+		return
+	}
+	r.redactString(a.Summary)
+	if a.StructuredData != nil {
+		a.StructuredData = r.redactValue(a.StructuredData).(map[string]any)
+	}
+	if len(a.StructuredDataMulti) > 0 {
+		var v any
+		if err := json.Unmarshal(a.StructuredDataMulti, &v); err == nil {
+			if b, err := json.Marshal(r.redactValue(v)); err == nil {
+				a.StructuredDataMulti = b
+			}
+		}
+	}
+	if s, ok := a.SuccessEvaluation.(string); ok {
+		a.SuccessEvaluation = r.String(s)
+	}
+	r.redactExtra(reflect.ValueOf(a))
+}
+
+// redactAssistant redacts the variable values of a call's assistant and the
+// messages they are substituted into
+func (r *Redactor) redactAssistant(a *Assistant) {
+	if a == nil {
+		return
+	}
+	if a.VariableValues != nil {
+		a.VariableValues = r.redactValue(a.VariableValues).(map[string]any)
+	}
+	r.redactString(a.FirstMessage)
+	r.redactString(a.EndCallMessage)
+	r.redactString(a.VoicemailMessage)
+	if a.Model != nil {
+		for i := range a.Model.Messages {
+			a.Model.Messages[i].Content = r.String(a.Model.Messages[i].Content)
+		}
+	}
+}
+
+// redactExtra redacts the strings in every Extra map reachable from v, so
+// fields this package does not model, such as the legacy top-level
+// transcript and summary, are not stored in the clear
+func (r *Redactor) redactExtra(v reflect.Value) {
+	switch v.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		if !v.IsNil() {
+			r.redactExtra(v.Elem())
+		}
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			r.redactExtra(v.Index(i))
+		}
+	case reflect.Struct:
+		t := v.Type()
+		for i := 0; i < t.NumField(); i++ {
+			f := t.Field(i)
+			if !f.IsExported() {
+				continue
+			}
+			if f.Name == "Extra" && f.Type == rawMapType {
+				extra := v.Field(i).Interface().(map[string]json.RawMessage)
+				for k, raw := range extra {
+					extra[k] = r.redactRaw(raw)
+				}
+				continue
+			}
+			r.redactExtra(v.Field(i))
+		}
+	}
+}
+
+// redactRaw redacts the strings in a JSON value, keeping numbers as written
+func (r *Redactor) redactRaw(raw json.RawMessage) json.RawMessage {
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	dec.UseNumber()
+	var v any
+	if err := dec.Decode(&v); err != nil {
+		return raw
+	}
+	b, err := json.Marshal(r.redactValue(v))
+	if err != nil {
+		return raw
+	}
+	return b
+}
+
+func (r *Redactor) redactMessages(messages []Message) {
+	for i := range messages {
+		m := &messages[i]
+		m.Message = r.String(m.Message)
+		m.Result = r.String(m.Result)
+		for j := range m.ToolCalls {
+			m.ToolCalls[j].Function.Arguments = r.String(m.ToolCalls[j].Function.Arguments)
+		}
+	}
+}
+
+func (r *Redactor) redactOpenAIMessages(messages []openai.ChatCompletionMessage) {
+	for i := range messages {
+		m := &messages[i]
+		m.Content = r.String(m.Content)
+		for j := range m.MultiContent {
+			m.MultiContent[j].Text = r.String(m.MultiContent[j].Text)
+		}
+		for j := range m.ToolCalls {
+			m.ToolCalls[j].Function.Arguments = r.String(m.ToolCalls[j].Function.Arguments)
+		}
+		if m.FunctionCall != nil {
+			m.FunctionCall.Arguments = r.String(m.FunctionCall.Arguments)
+		}
+	}
+}
+
+// redactCustomer redacts the customer's number, name and SIP URI as a
+// whole, since they are personal data whatever their format
+func (r *Redactor) redactCustomer(c *Customer) {
+	if c == nil {
+		return
+	}
+	if c.Number != "" {
+		if redacted := r.String(c.Number); redacted != c.Number {
+			c.Number = redacted
+		} else {
+			c.Number = r.replacement(PIIPhone, c.Number)
+		}
+	}
+	if c.Name != "" {
+		c.Name = r.replacement(PIIName, c.Name)
+	}
+	if c.SipURI != "" {
+		c.SipURI = r.replacement(PIISIP, c.SipURI)
+	}
+}
+
+func (r *Redactor) redactString(s *string) {
+	if s != nil {
+		*s = r.String(*s)
+	}
+}
+
+// redactValue redacts the strings in a decoded JSON value
+func (r *Redactor) redactValue(v any) any {
+	switch v := v.(type) {
+	case string:
+		return r.String(v)
+	case map[string]any:
+		for k, e := range v {
+			v[k] = r.redactValue(e)
+		}
+	case []any:
+		for i, e := range v {
+			v[i] = r.redactValue(e)
+		}
+	}
+	return v
+}
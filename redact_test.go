@@ -0,0 +1,97 @@
+package vapi
+
+import (
+	"encoding/json"
+	"regexp"
+	"strings"
+	"testing"
+
+	openai "github.com/sashabaranov/go-openai"
+)
+
+func TestRedactor_String(t *testing.T) {
+	r := NewRedactor(RedactPattern{Kind: "account-id", Pattern: regexp.MustCompile(`\bACC-\d{6}\b`)})
+	for _, tc := range []struct{ in, want string }{
+		{"call me at (555) 123-4567 or +44 7911123456", "call me at [PHONE] or [PHONE]"},
+		{"my email is Jane.Doe@example.co.uk.", "my email is [EMAIL]."},
+		{"card 4111 1111 1111 1111 exp 12/26", "card [CARD] exp 12/26"},
+		{"order 4111 1111 1111 1112 shipped", "order 4111 1111 1111 1112 shipped"},
+		{"ssn 123-45-6789", "ssn [SSN]"},
+		{"ssn 123456789", "ssn [SSN]"},
+		{"ssn 123 45 6789", "ssn [SSN]"},
+		{"account ACC-123456", "account [ACCOUNT-ID]"},
+		{"it costs $12.50 at 3pm", "it costs $12.50 at 3pm"},
+	} {
+		if got := r.String(tc.in); got != tc.want {
+			t.Errorf("String(%q) = %q, want %q", tc.in, got, tc.want)
+		}
+	}
+}
+
+func TestRedactor_Report(t *testing.T) {
+	report := loadReportFixture(t)
+	report.Artifact.Messages[3].Message = "My number is 555-123-4567."
+	report.Artifact.Transcript = "User: My number is 555-123-4567."
+	report.Artifact.MessagesOpenAIFormatted = []openai.ChatCompletionMessage{{Role: "user", Content: "My number is 555.123.4567"}}
+	report.Analysis = &Analysis{
+		StructuredData:      map[string]any{"caller": map[string]any{"phone": "+1 555 123 4567", "email": "a@b.io"}},
+		StructuredDataMulti: json.RawMessage(`[{"notes": "email a@b.io"}]`),
+	}
+	report.Customer = &Customer{Number: "+15551234567", Name: "Jane Doe", SipURI: "sip:jane@example.com"}
+	report.Extra = map[string]json.RawMessage{"transcript": json.RawMessage(`"User: call 555-123-4567"`)}
+	report.Call.Artifact = &Artifact{Extra: map[string]json.RawMessage{"notes": json.RawMessage(`{"count": 1.50, "email": "a@b.io"}`)}}
+	report.Call.AssistantOverrides = &Assistant{
+		VariableValues: map[string]any{"phone": "555-123-4567"},
+		Model:          &ModelConfig{Messages: []ModelMessage{{Role: "system", Content: "The caller's number is 555-123-4567."}}},
+	}
+
+	r := NewTokenizingRedactor([]byte("secret"), RedactPattern{Kind: "account-id", Pattern: regexp.MustCompile(`\bACC-\d{6}\b`)})
+	r.RedactReport(report)
+
+	token := r.String("5551234567")
+	if !strings.HasPrefix(token, "[PHONE:") {
+		t.Fatalf("token = %q", token)
+	}
+	for name, got := range map[string]string{
+		"message":    report.Artifact.Messages[3].Message,
+		"transcript": report.Artifact.Transcript,
+		"openai":     report.Artifact.MessagesOpenAIFormatted[0].Content,
+		"analysis":   report.Analysis.StructuredData["caller"].(map[string]any)["phone"].(string),
+		"customer":   report.Customer.Number,
+		"extra":      string(report.Extra["transcript"]),
+		"variables":  report.Call.AssistantOverrides.VariableValues["phone"].(string),
+		"model":      report.Call.AssistantOverrides.Model.Messages[0].Content,
+	} {
+		if !strings.Contains(got, token) {
+			t.Errorf("%s = %q, want it to contain %s", name, got, token)
+		}
+	}
+	if strings.Contains(string(report.Analysis.StructuredDataMulti), "a@b.io") {
+		t.Errorf("StructuredDataMulti = %s", report.Analysis.StructuredDataMulti)
+	}
+	if !strings.HasPrefix(report.Customer.Name, "[NAME:") || !strings.HasPrefix(report.Customer.SipURI, "[SIP:") {
+		t.Errorf("customer name = %q, SIP URI = %q", report.Customer.Name, report.Customer.SipURI)
+	}
+	if got := string(report.Call.Artifact.Extra["notes"]); strings.Contains(got, "a@b.io") || !strings.Contains(got, "1.50") {
+		t.Errorf("nested Extra = %s", got)
+	}
+
+	account := r.String("account ACC-123456")
+	spaced := NewTokenizingRedactor([]byte("secret"), RedactPattern{Kind: "Account Number", Pattern: regexp.MustCompile(`\bAN\d{8}\b`)})
+	number := spaced.String("account AN12345678")
+	restorer := NewTokenizingRedactor([]byte("secret"))
+	restorer.LoadTokens(spaced.Tokens())
+	restorer.LoadTokens(r.Tokens())
+	if got := restorer.Restore(report.Artifact.Transcript); got != "User: My number is 555-123-4567." {
+		t.Errorf("Restore = %q", got)
+	}
+	if got := restorer.Restore(account); got != "account ACC-123456" {
+		t.Errorf("Restore custom kind = %q", got)
+	}
+	if got := restorer.Restore(number); got != "account AN12345678" {
+		t.Errorf("Restore kind with a space = %q", got)
+	}
+	if got := restorer.Restore(report.Customer.Name); got != "Jane Doe" {
+		t.Errorf("Restore customer name = %q", got)
+	}
+}
@@ -0,0 +1,50 @@
+package vapi
+
+import (
+	"context"
+	"net/http"
+)
+
+// GetSquad retrieves a squad by its ID
+func GetSquad(ctx context.Context, id string) (*Squad, error) {
+	var result Squad
+	if err := apiRequest(ctx, http.MethodGet, "/squad/"+id, nil, http.StatusOK, &result, "get squad"); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// CreateSquad creates a new squad with the given configuration
+func CreateSquad(ctx context.Context, squad *Squad) (*Squad, error) {
+	return createSquad(ctx, squad)
+}
+
+// UpdateSquad patches the squad with the given ID
+func UpdateSquad(ctx context.Context, id string, squad *Squad) (*Squad, error) {
+	patch := *squad
+	patch.ID = nil
+	return updateSquad(ctx, id, &patch)
+}
+
+// createSquad and updateSquad send any payload, such as the spec of a
+// manifest, so fields the caller did not set are left out
+func createSquad(ctx context.Context, payload any) (*Squad, error) {
+	var result Squad
+	if err := apiRequest(ctx, http.MethodPost, "/squad", payload, http.StatusCreated, &result, "create squad"); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+func updateSquad(ctx context.Context, id string, payload any) (*Squad, error) {
+	var result Squad
+	if err := apiRequest(ctx, http.MethodPatch, "/squad/"+id, payload, http.StatusOK, &result, "update squad"); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// DeleteSquad deletes the squad with the given ID
+func DeleteSquad(ctx context.Context, id string) error {
+	return apiRequest(ctx, http.MethodDelete, "/squad/"+id, nil, http.StatusOK, nil, "delete squad")
+}
@@ -0,0 +1,251 @@
+package vapi
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+
+	"github.com/chriscow/minds"
+)
+
+// StructuredDataSchema derives the structured data schema from the fields
+// of T, which must be a struct. Properties follow the json tags; fields
+// without omitempty are required, and description tags become descriptions.
+func StructuredDataSchema[T any]() (*minds.Definition, error) {
+	var zero T
+	schema, err := minds.GenerateSchema(zero)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate structured data schema: %w", err)
+	}
+	if schema.Type != minds.Object {
+		return nil, fmt.Errorf("failed to generate structured data schema: %T is not a struct", zero)
+	}
+	return schema, nil
+}
+
+// NewStructuredDataPlan returns an enabled plan extracting T, with the
+// schema derived from T so the two cannot drift apart:
+//
+//	type Order struct {
+//		OrderID string `json:"orderId" description:"The order the caller asked about"`
+//		Refund  bool   `json:"refund"`
+//	}
+//	plan, err := vapi.NewStructuredDataPlan[Order](messages...)
+//	...
+//	order, err := vapi.DecodeStructuredData[Order](call.Analysis)
+func NewStructuredDataPlan[T any](messages ...ModelMessage) (*StructuredDataPlan, error) {
+	schema, err := StructuredDataSchema[T]()
+	if err != nil {
+		return nil, err
+	}
+	return &StructuredDataPlan{Enabled: true, Schema: schema, Messages: messages}, nil
+}
+
+// DecodeStructuredData validates the call's structured data against the
+// schema of T and decodes it into a T. Validation problems are returned as
+// ValidationErrors.
+func DecodeStructuredData[T any](a *Analysis) (T, error) {
+	var data any
+	if a != nil && a.StructuredData != nil {
+		data = a.StructuredData
+	}
+	return decodeStructuredData[T](data, "structuredData")
+}
+
+// DecodeStructuredDataMulti decodes the StructuredDataMulti result stored
+// under key, the Key of the StructuredDataMulti plan that produced it, into
+// a T. The result is validated against the schema of T first.
+func DecodeStructuredDataMulti[T any](a *Analysis, key string) (T, error) {
+	var zero T
+	if a == nil || len(a.StructuredDataMulti) == 0 {
+		return zero, fmt.Errorf("no structured data for %q", key)
+	}
+	results, err := structuredDataMulti(a.StructuredDataMulti)
+	if err != nil {
+		return zero, err
+	}
+	data, ok := results[key]
+	if !ok {
+		return zero, fmt.Errorf("no structured data for %q", key)
+	}
+	return decodeStructuredData[T](data, "structuredDataMulti."+key)
+}
+
+// StructuredDataKeys returns the keys of the StructuredDataMulti results
+func (a *Analysis) StructuredDataKeys() ([]string, error) {
+	if a == nil || len(a.StructuredDataMulti) == 0 {
+		return nil, nil
+	}
+	results, err := structuredDataMulti(a.StructuredDataMulti)
+	if err != nil {
+		return nil, err
+	}
+	keys := make([]string, 0, len(results))
+	for k := range results {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys, nil
+}
+
+// ValidateStructuredData checks data, as decoded from JSON, against schema.
+// Every problem is returned as ValidationErrors with the JSON path of the
+// value. Properties the schema does not mention are allowed, and null is
+// accepted for optional properties.
+func ValidateStructuredData(schema minds.Definition, data any) error {
+	var v validator
+	validateSchema(&v, schema, data, "")
+	return v.err()
+}
+
+func decodeStructuredData[T any](data any, path string) (T, error) {
+	var result T
+	schema, err := StructuredDataSchema[T]()
+	if err != nil {
+		return result, err
+	}
+	if data == nil {
+		return result, ValidationErrors{{Field: path, Message: "is missing"}}
+	}
+	var v validator
+	validateSchema(&v, *schema, data, path)
+	if err := v.err(); err != nil {
+		return result, err
+	}
+
+	b, err := json.Marshal(data)
+	if err != nil {
+		return result, err
+	}
+	if err := json.Unmarshal(b, &result); err != nil {
+		return result, fmt.Errorf("failed to decode %s: %w", path, err)
+	}
+	return result, nil
+}
+
+// structuredDataMulti indexes the StructuredDataMulti results by key. It
+// accepts an object of key to result, or an array of either single-key
+// objects or {"key": ..., "structuredData": ...} entries.
+func structuredDataMulti(raw json.RawMessage) (map[string]any, error) {
+	var v any
+	if err := json.Unmarshal(raw, &v); err != nil {
+		return nil, fmt.Errorf("failed to decode structuredDataMulti: %w", err)
+	}
+	switch v := v.(type) {
+	case map[string]any:
+		return v, nil
+	case []any:
+		results := map[string]any{}
+		for i, entry := range v {
+			obj, ok := entry.(map[string]any)
+			if !ok {
+				return nil, fmt.Errorf("failed to decode structuredDataMulti[%d]: not an object", i)
+			}
+			if key, ok := obj["key"].(string); ok {
+				for _, field := range []string{"structuredData", "result", "data"} {
+					if data, ok := obj[field]; ok {
+						results[key] = data
+						break
+					}
+				}
+				continue
+			}
+			for k, data := range obj {
+				results[k] = data
+			}
+		}
+		return results, nil
+	}
+	return nil, fmt.Errorf("failed to decode structuredDataMulti: not an object or array")
+}
+
+func validateSchema(v *validator, schema minds.Definition, data any, path string) {
+	field := path
+	if field == "" {
+		field = "$"
+	}
+	if data == nil && schema.Type != minds.Null {
+		v.addf(field, "must be %s, got null", schema.Type)
+		return
+	}
+
+	switch schema.Type {
+	case minds.Object:
+		obj, ok := data.(map[string]any)
+		if !ok {
+			v.addf(field, "must be an object, got %s", jsonTypeName(data))
+			return
+		}
+		required := map[string]bool{}
+		for _, name := range schema.Required {
+			required[name] = true
+			if _, ok := obj[name]; !ok {
+				v.addf(joinPath(path, name), "is required")
+			}
+		}
+		names := make([]string, 0, len(schema.Properties))
+		for name := range schema.Properties {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			value, ok := obj[name]
+			if !ok || (value == nil && !required[name]) {
+				continue
+			}
+			validateSchema(v, schema.Properties[name], value, joinPath(path, name))
+		}
+	case minds.Array:
+		items, ok := data.([]any)
+		if !ok {
+			v.addf(field, "must be an array, got %s", jsonTypeName(data))
+			return
+		}
+		if schema.Items == nil {
+			return
+		}
+		for i, item := range items {
+			validateSchema(v, *schema.Items, item, fmt.Sprintf("%s[%d]", path, i))
+		}
+	case minds.String:
+		s, ok := data.(string)
+		if !ok {
+			v.addf(field, "must be a string, got %s", jsonTypeName(data))
+			return
+		}
+		if len(schema.Enum) > 0 && !contains(schema.Enum, s) {
+			v.addf(field, "must be one of %v, got %q", schema.Enum, s)
+		}
+	case minds.Integer:
+		if f, ok := data.(float64); !ok || f != math.Trunc(f) {
+			v.addf(field, "must be an integer, got %s", jsonTypeName(data))
+		}
+	case minds.Number:
+		if _, ok := data.(float64); !ok {
+			v.addf(field, "must be a number, got %s", jsonTypeName(data))
+		}
+	case minds.Boolean:
+		if _, ok := data.(bool); !ok {
+			v.addf(field, "must be a boolean, got %s", jsonTypeName(data))
+		}
+	}
+}
+
+func jsonTypeName(data any) string {
+	switch data := data.(type) {
+	case nil:
+		return "null"
+	case map[string]any:
+		return "an object"
+	case []any:
+		return "an array"
+	case string:
+		return fmt.Sprintf("string %q", data)
+	case float64:
+		return fmt.Sprintf("number %g", data)
+	case bool:
+		return fmt.Sprintf("boolean %t", data)
+	}
+	return fmt.Sprintf("%T", data)
+}
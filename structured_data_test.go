@@ -0,0 +1,87 @@
+package vapi
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/chriscow/minds"
+)
+
+type testOrderData struct {
+	OrderID string   `json:"orderId" description:"The order the caller asked about"`
+	Items   []string `json:"items"`
+	Refund  bool     `json:"refund"`
+	Rating  int      `json:"rating,omitempty"`
+}
+
+func TestNewStructuredDataPlan(t *testing.T) {
+	plan, err := NewStructuredDataPlan[testOrderData](ModelMessage{Role: "system", Content: "Extract the order."})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !plan.Enabled || plan.Schema.Type != minds.Object {
+		t.Fatalf("plan = %+v", plan)
+	}
+	if got := plan.Schema.Properties["orderId"]; got.Type != minds.String || got.Description != "The order the caller asked about" {
+		t.Errorf("orderId schema = %+v", got)
+	}
+	if want := []string{"orderId", "items", "refund"}; !reflect.DeepEqual(plan.Schema.Required, want) {
+		t.Errorf("Required = %v, want %v", plan.Schema.Required, want)
+	}
+
+	if _, err := StructuredDataSchema[string](); err == nil {
+		t.Error("StructuredDataSchema[string]() succeeded, want error")
+	}
+}
+
+func TestDecodeStructuredData(t *testing.T) {
+	a := &Analysis{StructuredData: map[string]any{"orderId": "A-1", "items": []any{"lamp"}, "refund": true, "rating": nil}}
+	order, err := DecodeStructuredData[testOrderData](a)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := (testOrderData{OrderID: "A-1", Items: []string{"lamp"}, Refund: true}); !reflect.DeepEqual(order, want) {
+		t.Errorf("order = %+v, want %+v", order, want)
+	}
+
+	a.StructuredData = map[string]any{"orderId": float64(7), "items": []any{"lamp", false}, "rating": 4.5}
+	_, err = DecodeStructuredData[testOrderData](a)
+	var verrs ValidationErrors
+	if !errors.As(err, &verrs) {
+		t.Fatalf("err = %v, want ValidationErrors", err)
+	}
+	got := map[string]bool{}
+	for _, e := range verrs {
+		got[e.Field] = true
+	}
+	for _, field := range []string{"structuredData.refund", "structuredData.orderId", "structuredData.items[1]", "structuredData.rating"} {
+		if !got[field] {
+			t.Errorf("no error for %s in %v", field, verrs)
+		}
+	}
+
+	if _, err := DecodeStructuredData[testOrderData](&Analysis{}); err == nil {
+		t.Error("decoding missing structured data succeeded")
+	}
+}
+
+func TestDecodeStructuredDataMulti(t *testing.T) {
+	for _, raw := range []string{
+		`{"order": {"orderId": "A-2", "items": [], "refund": false}, "sentiment": "calm"}`,
+		`[{"key": "order", "structuredData": {"orderId": "A-2", "items": [], "refund": false}}, {"sentiment": "calm"}]`,
+	} {
+		a := &Analysis{StructuredDataMulti: json.RawMessage(raw)}
+		order, err := DecodeStructuredDataMulti[testOrderData](a, "order")
+		if err != nil || order.OrderID != "A-2" {
+			t.Errorf("%s: order = %+v, %v", raw, order, err)
+		}
+		if keys, err := a.StructuredDataKeys(); err != nil || !reflect.DeepEqual(keys, []string{"order", "sentiment"}) {
+			t.Errorf("%s: keys = %v, %v", raw, keys, err)
+		}
+		if _, err := DecodeStructuredDataMulti[testOrderData](a, "missing"); err == nil {
+			t.Errorf("%s: decoding a missing key succeeded", raw)
+		}
+	}
+}
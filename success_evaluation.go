@@ -0,0 +1,386 @@
+package vapi
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// DescriptiveRating is a DescriptiveScale success evaluation
+type DescriptiveRating string
+
+const (
+	RatingExcellent DescriptiveRating = "Excellent"
+	RatingGood      DescriptiveRating = "Good"
+	RatingFair      DescriptiveRating = "Fair"
+	RatingPoor      DescriptiveRating = "Poor"
+)
+
+// LikertRating is a LikertScale success evaluation
+type LikertRating string
+
+const (
+	LikertStronglyAgree    LikertRating = "Strongly Agree"
+	LikertAgree            LikertRating = "Agree"
+	LikertNeutral          LikertRating = "Neutral"
+	LikertDisagree         LikertRating = "Disagree"
+	LikertStronglyDisagree LikertRating = "Strongly Disagree"
+)
+
+// ChecklistItem is one criterion of a Checklist success evaluation
+type ChecklistItem struct {
+	Criterion string
+	Passed    bool
+}
+
+// MatrixScore is the level one criterion reached in a Matrix or
+// AutomaticRubric success evaluation
+type MatrixScore struct {
+	Criterion string
+	Level     string
+}
+
+// SuccessEvaluationError reports a success evaluation that does not fit the
+// rubric it was read as
+type SuccessEvaluationError struct {
+	Rubric string
+	Value  any
+	Msg    string
+}
+
+func (e *SuccessEvaluationError) Error() string {
+	return fmt.Sprintf("success evaluation %#v is not a valid %s result: %s", e.Value, e.Rubric, e.Msg)
+}
+
+// EvaluationRubric returns the plan's rubric, or PassFail when none is set
+func (p *SuccessEvaluationPlan) EvaluationRubric() string {
+	if p == nil || p.Rubric == nil || *p.Rubric == "" {
+		return RubricPassFail
+	}
+	return *p.Rubric
+}
+
+func (a *Analysis) evaluation(rubric string) (any, error) {
+	if a == nil || a.SuccessEvaluation == nil {
+		return nil, &SuccessEvaluationError{Rubric: rubric, Msg: "no success evaluation"}
+	}
+	return a.SuccessEvaluation, nil
+}
+
+// PassFail reads a PassFail evaluation. Besides booleans it accepts the
+// strings "true", "false", "pass" and "fail".
+func (a *Analysis) PassFail() (bool, error) {
+	v, err := a.evaluation(RubricPassFail)
+	if err != nil {
+		return false, err
+	}
+	switch v := v.(type) {
+	case bool:
+		return v, nil
+	case string:
+		switch strings.ToLower(strings.TrimSpace(strings.Trim(v, `."'`))) {
+		case "true", "pass", "passed", "yes", "success", "successful":
+			return true, nil
+		case "false", "fail", "failed", "no", "failure", "unsuccessful":
+			return false, nil
+		}
+	}
+	return false, &SuccessEvaluationError{Rubric: RubricPassFail, Value: v, Msg: "want true or false"}
+}
+
+// NumericScore reads a NumericScale evaluation, a whole number from 1 to 10.
+// Strings like "8" and "8/10" are accepted.
+func (a *Analysis) NumericScore() (int, error) {
+	v, err := a.evaluation(RubricNumericScale)
+	if err != nil {
+		return 0, err
+	}
+	f, ok := evaluationNumber(v, "/10")
+	if !ok || f != math.Trunc(f) {
+		return 0, &SuccessEvaluationError{Rubric: RubricNumericScale, Value: v, Msg: "want a whole number"}
+	}
+	if f < 1 || f > 10 {
+		return 0, &SuccessEvaluationError{Rubric: RubricNumericScale, Value: v, Msg: "want a score from 1 to 10"}
+	}
+	return int(f), nil
+}
+
+// PercentageScore reads a PercentageScale evaluation, from 0 to 100. Strings
+// like "85%" are accepted.
+func (a *Analysis) PercentageScore() (float64, error) {
+	v, err := a.evaluation(RubricPercentageScale)
+	if err != nil {
+		return 0, err
+	}
+	f, ok := evaluationNumber(v, "%")
+	if !ok {
+		return 0, &SuccessEvaluationError{Rubric: RubricPercentageScale, Value: v, Msg: "want a percentage"}
+	}
+	if f < 0 || f > 100 {
+		return 0, &SuccessEvaluationError{Rubric: RubricPercentageScale, Value: v, Msg: "want a percentage from 0 to 100"}
+	}
+	return f, nil
+}
+
+// DescriptiveRating reads a DescriptiveScale evaluation
+func (a *Analysis) DescriptiveRating() (DescriptiveRating, error) {
+	v, err := a.evaluation(RubricDescriptiveScale)
+	if err != nil {
+		return "", err
+	}
+	ratings := []DescriptiveRating{RatingExcellent, RatingGood, RatingFair, RatingPoor}
+	if s, ok := v.(string); ok {
+		for _, r := range ratings {
+			if sameRating(s, string(r)) {
+				return r, nil
+			}
+		}
+	}
+	return "", &SuccessEvaluationError{Rubric: RubricDescriptiveScale, Value: v, Msg: "want Excellent, Good, Fair or Poor"}
+}
+
+// LikertRating reads a LikertScale evaluation
+func (a *Analysis) LikertRating() (LikertRating, error) {
+	v, err := a.evaluation(RubricLikertScale)
+	if err != nil {
+		return "", err
+	}
+	ratings := []LikertRating{LikertStronglyAgree, LikertAgree, LikertNeutral, LikertDisagree, LikertStronglyDisagree}
+	if s, ok := v.(string); ok {
+		for _, r := range ratings {
+			if sameRating(s, string(r)) {
+				return r, nil
+			}
+		}
+	}
+	return "", &SuccessEvaluationError{Rubric: RubricLikertScale, Value: v, Msg: "want Strongly Agree, Agree, Neutral, Disagree or Strongly Disagree"}
+}
+
+// Checklist reads a Checklist evaluation. It accepts a JSON object of
+// criteria to booleans, or one criterion per line such as "- [x] Greeted the
+// caller" or "Confirmed the order: yes".
+func (a *Analysis) Checklist() ([]ChecklistItem, error) {
+	v, err := a.evaluation(RubricChecklist)
+	if err != nil {
+		return nil, err
+	}
+	fail := func(msg string) ([]ChecklistItem, error) {
+		return nil, &SuccessEvaluationError{Rubric: RubricChecklist, Value: v, Msg: msg}
+	}
+
+	if obj, ok := evaluationObject(v); ok {
+		var items []ChecklistItem
+		for _, k := range sortedKeys(obj) {
+			passed, ok := checkValue(obj[k])
+			if !ok {
+				return fail(fmt.Sprintf("criterion %q is %v, want true or false", k, obj[k]))
+			}
+			items = append(items, ChecklistItem{Criterion: k, Passed: passed})
+		}
+		return items, nil
+	}
+
+	s, ok := v.(string)
+	if !ok {
+		return fail("want a list of criteria")
+	}
+	var items []ChecklistItem
+	for _, line := range evaluationLines(s) {
+		item, ok := parseChecklistLine(line)
+		if !ok {
+			return fail(fmt.Sprintf("cannot tell whether %q passed", line))
+		}
+		items = append(items, item)
+	}
+	if len(items) == 0 {
+		return fail("no criteria")
+	}
+	return items, nil
+}
+
+// Matrix reads a Matrix evaluation. It accepts a JSON object of criteria to
+// levels or scores, or one "criterion: level" per line.
+func (a *Analysis) Matrix() ([]MatrixScore, error) {
+	return a.matrix(RubricMatrix)
+}
+
+// AutomaticRubric reads an AutomaticRubric evaluation, which has the same
+// shape as a Matrix one
+func (a *Analysis) AutomaticRubric() ([]MatrixScore, error) {
+	return a.matrix(RubricAutomaticRubric)
+}
+
+// Evaluation reads the success evaluation as the rubric of plan calls for,
+// so callers need not switch on the rubric themselves. The result is a
+// bool for PassFail, an int for NumericScale, a float64 for
+// PercentageScale, a DescriptiveRating, a LikertRating, a []ChecklistItem,
+// or a []MatrixScore for Matrix and AutomaticRubric.
+func (a *Analysis) Evaluation(plan *SuccessEvaluationPlan) (any, error) {
+	switch rubric := plan.EvaluationRubric(); rubric {
+	case RubricPassFail:
+		return a.PassFail()
+	case RubricNumericScale:
+		return a.NumericScore()
+	case RubricPercentageScale:
+		return a.PercentageScore()
+	case RubricDescriptiveScale:
+		return a.DescriptiveRating()
+	case RubricLikertScale:
+		return a.LikertRating()
+	case RubricChecklist:
+		return a.Checklist()
+	case RubricMatrix, RubricAutomaticRubric:
+		return a.matrix(rubric)
+	default:
+		return nil, fmt.Errorf("unknown success evaluation rubric %q", rubric)
+	}
+}
+
+func (a *Analysis) matrix(rubric string) ([]MatrixScore, error) {
+	v, err := a.evaluation(rubric)
+	if err != nil {
+		return nil, err
+	}
+	fail := func(msg string) ([]MatrixScore, error) {
+		return nil, &SuccessEvaluationError{Rubric: rubric, Value: v, Msg: msg}
+	}
+
+	if obj, ok := evaluationObject(v); ok {
+		var scores []MatrixScore
+		for _, k := range sortedKeys(obj) {
+			switch level := obj[k].(type) {
+			case string:
+				scores = append(scores, MatrixScore{Criterion: k, Level: level})
+			case float64:
+				scores = append(scores, MatrixScore{Criterion: k, Level: strconv.FormatFloat(level, 'f', -1, 64)})
+			default:
+				return fail(fmt.Sprintf("criterion %q has no level", k))
+			}
+		}
+		return scores, nil
+	}
+
+	s, ok := v.(string)
+	if !ok {
+		return fail("want criteria and levels")
+	}
+	var scores []MatrixScore
+	for _, line := range evaluationLines(s) {
+		criterion, level, found := strings.Cut(line, ":")
+		if !found || strings.TrimSpace(level) == "" {
+			return fail(fmt.Sprintf("line %q has no level", line))
+		}
+		scores = append(scores, MatrixScore{Criterion: strings.TrimSpace(criterion), Level: strings.TrimSpace(level)})
+	}
+	if len(scores) == 0 {
+		return fail("no criteria")
+	}
+	return scores, nil
+}
+
+// evaluationNumber reads a number, or a string holding one with an optional
+// suffix such as "%"
+func evaluationNumber(v any, suffix string) (float64, bool) {
+	switch v := v.(type) {
+	case float64:
+		return v, true
+	case int:
+		return float64(v), true
+	case string:
+		s := strings.TrimSpace(strings.TrimSuffix(strings.TrimSpace(v), "."))
+		s = strings.TrimSpace(strings.TrimSuffix(s, suffix))
+		f, err := strconv.ParseFloat(s, 64)
+		return f, err == nil
+	}
+	return 0, false
+}
+
+// evaluationObject returns v as a JSON object, decoding it from a string if
+// the evaluation model answered with JSON text
+func evaluationObject(v any) (map[string]any, bool) {
+	switch v := v.(type) {
+	case map[string]any:
+		return v, true
+	case string:
+		var obj map[string]any
+		s := strings.TrimSpace(v)
+		if strings.HasPrefix(s, "{") && json.Unmarshal([]byte(s), &obj) == nil {
+			return obj, true
+		}
+	}
+	return nil, false
+}
+
+// evaluationLines splits s into non-empty lines without list markers
+func evaluationLines(s string) []string {
+	var lines []string
+	for _, line := range strings.Split(s, "\n") {
+		line = strings.TrimSpace(line)
+		line = strings.TrimLeft(line, "-*• ")
+		if line != "" {
+			lines = append(lines, line)
+		}
+	}
+	return lines
+}
+
+func parseChecklistLine(line string) (ChecklistItem, bool) {
+	lower := strings.ToLower(line)
+	switch {
+	case strings.HasPrefix(lower, "[x]"), strings.HasPrefix(lower, "✅"), strings.HasPrefix(lower, "✓"), strings.HasPrefix(lower, "✔"):
+		return ChecklistItem{Criterion: trimChecklistMark(line), Passed: true}, true
+	case strings.HasPrefix(lower, "[ ]"), strings.HasPrefix(lower, "❌"), strings.HasPrefix(lower, "✗"), strings.HasPrefix(lower, "✘"):
+		return ChecklistItem{Criterion: trimChecklistMark(line), Passed: false}, true
+	}
+	i := strings.LastIndex(line, ":")
+	if i < 0 {
+		return ChecklistItem{}, false
+	}
+	passed, ok := checkValue(strings.TrimSpace(line[i+1:]))
+	return ChecklistItem{Criterion: strings.TrimSpace(line[:i]), Passed: passed}, ok
+}
+
+func trimChecklistMark(line string) string {
+	for _, mark := range []string{"[x]", "[X]", "[ ]", "✅", "✓", "✔", "❌", "✗", "✘"} {
+		if strings.HasPrefix(line, mark) {
+			return strings.TrimSpace(line[len(mark):])
+		}
+	}
+	return line
+}
+
+// checkValue reads whether a checklist criterion passed
+func checkValue(v any) (bool, bool) {
+	switch v := v.(type) {
+	case bool:
+		return v, true
+	case string:
+		switch strings.ToLower(strings.Trim(strings.TrimSpace(v), ".")) {
+		case "true", "yes", "pass", "passed", "met", "done", "✅", "✓", "✔":
+			return true, true
+		case "false", "no", "fail", "failed", "not met", "missed", "❌", "✗", "✘":
+			return false, true
+		}
+	}
+	return false, false
+}
+
+// sameRating compares a rating ignoring case, spacing and trailing
+// punctuation
+func sameRating(s, rating string) bool {
+	s = strings.Trim(strings.TrimSpace(s), `."'`)
+	return strings.EqualFold(strings.Join(strings.Fields(s), " "), rating) ||
+		strings.EqualFold(strings.ReplaceAll(s, " ", ""), strings.ReplaceAll(rating, " ", ""))
+}
+
+func sortedKeys(m map[string]any) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,98 @@
+package vapi
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestAnalysis_SuccessEvaluation(t *testing.T) {
+	eval := func(v any) *Analysis { return &Analysis{SuccessEvaluation: v} }
+
+	if ok, err := eval(true).PassFail(); err != nil || !ok {
+		t.Errorf("PassFail(true) = %v, %v", ok, err)
+	}
+	if ok, err := eval("false").PassFail(); err != nil || ok {
+		t.Errorf(`PassFail("false") = %v, %v`, ok, err)
+	}
+	if score, err := eval("8/10").NumericScore(); err != nil || score != 8 {
+		t.Errorf(`NumericScore("8/10") = %d, %v`, score, err)
+	}
+	if pct, err := eval("85%").PercentageScore(); err != nil || pct != 85 {
+		t.Errorf(`PercentageScore("85%%") = %v, %v`, pct, err)
+	}
+	if r, err := eval("excellent.").DescriptiveRating(); err != nil || r != RatingExcellent {
+		t.Errorf("DescriptiveRating = %q, %v", r, err)
+	}
+	if r, err := eval("strongly  agree").LikertRating(); err != nil || r != LikertStronglyAgree {
+		t.Errorf("LikertRating = %q, %v", r, err)
+	}
+
+	items, err := eval("- [x] Greeted the caller\n- [ ] Offered a refund\n- Confirmed the address: yes").Checklist()
+	want := []ChecklistItem{{"Greeted the caller", true}, {"Offered a refund", false}, {"Confirmed the address", true}}
+	if err != nil || !reflect.DeepEqual(items, want) {
+		t.Errorf("Checklist = %+v, %v", items, err)
+	}
+	items, err = eval(`{"greeting": true, "refund": "no"}`).Checklist()
+	if err != nil || !reflect.DeepEqual(items, []ChecklistItem{{"greeting", true}, {"refund", false}}) {
+		t.Errorf("Checklist from JSON = %+v, %v", items, err)
+	}
+
+	scores, err := eval(map[string]any{"empathy": "High", "accuracy": float64(3)}).Matrix()
+	if err != nil || !reflect.DeepEqual(scores, []MatrixScore{{"accuracy", "3"}, {"empathy", "High"}}) {
+		t.Errorf("Matrix = %+v, %v", scores, err)
+	}
+
+	var evalErr *SuccessEvaluationError
+	if _, err := eval("no levels here").AutomaticRubric(); !errors.As(err, &evalErr) || evalErr.Rubric != RubricAutomaticRubric {
+		t.Errorf("AutomaticRubric error = %v, want it to name AutomaticRubric", err)
+	}
+
+	rubric := RubricLikertScale
+	if v, err := eval("Agree").Evaluation(&SuccessEvaluationPlan{Rubric: &rubric}); err != nil || v != LikertAgree {
+		t.Errorf("Evaluation(LikertScale) = %v, %v", v, err)
+	}
+	if v, err := eval("pass").Evaluation(nil); err != nil || v != true {
+		t.Errorf("Evaluation(nil) = %v, %v", v, err)
+	}
+	rubric = RubricAutomaticRubric
+	if v, err := eval("clarity: good").Evaluation(&SuccessEvaluationPlan{Rubric: &rubric}); err != nil || !reflect.DeepEqual(v, []MatrixScore{{"clarity", "good"}}) {
+		t.Errorf("Evaluation(AutomaticRubric) = %v, %v", v, err)
+	}
+
+	for name, err := range map[string]error{
+		"NumericScore out of range": func() error { _, err := eval(float64(11)).NumericScore(); return err }(),
+		"NumericScore fraction":     func() error { _, err := eval(7.5).NumericScore(); return err }(),
+		"PassFail from number":      func() error { _, err := eval(float64(1)).PassFail(); return err }(),
+		"DescriptiveRating unknown": func() error { _, err := eval("Great").DescriptiveRating(); return err }(),
+		"missing":                   func() error { _, err := (*Analysis)(nil).PercentageScore(); return err }(),
+	} {
+		var evalErr *SuccessEvaluationError
+		if !errors.As(err, &evalErr) {
+			t.Errorf("%s: err = %v, want *SuccessEvaluationError", name, err)
+		}
+	}
+}
+
+func TestAnalysisPlan_SuccessEvaluationPlan(t *testing.T) {
+	var plan AnalysisPlan
+	if err := json.Unmarshal([]byte(`{"successEvaluationPlan": {"rubric": "NumericScale", "timeoutSeconds": 90}}`), &plan); err != nil {
+		t.Fatal(err)
+	}
+	if plan.SuccessEvaluationPlan.EvaluationRubric() != RubricNumericScale || plan.Extra != nil {
+		t.Errorf("plan = %+v", plan)
+	}
+
+	a := &Assistant{AnalysisPlan: &plan}
+	err := a.Validate()
+	if err == nil || !strings.Contains(err.Error(), "analysisPlan.successEvaluationPlan.timeoutSeconds") {
+		t.Errorf("Validate() = %v", err)
+	}
+
+	var none *SuccessEvaluationPlan
+	if none.EvaluationRubric() != RubricPassFail {
+		t.Errorf("default rubric = %s", none.EvaluationRubric())
+	}
+}
@@ -0,0 +1,50 @@
+package vapi
+
+import (
+	"context"
+	"net/http"
+)
+
+// GetTool retrieves a tool by its ID
+func GetTool(ctx context.Context, id string) (*Tool, error) {
+	var result Tool
+	if err := apiRequest(ctx, http.MethodGet, "/tool/"+id, nil, http.StatusOK, &result, "get tool"); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// CreateTool creates a new tool with the given configuration
+func CreateTool(ctx context.Context, tool *Tool) (*Tool, error) {
+	return createTool(ctx, tool)
+}
+
+// UpdateTool patches the tool with the given ID
+func UpdateTool(ctx context.Context, id string, tool *Tool) (*Tool, error) {
+	patch := *tool
+	patch.ID = nil
+	return updateTool(ctx, id, &patch)
+}
+
+// createTool and updateTool send any payload, such as the spec of a
+// manifest, so fields the caller did not set are left out
+func createTool(ctx context.Context, payload any) (*Tool, error) {
+	var result Tool
+	if err := apiRequest(ctx, http.MethodPost, "/tool", payload, http.StatusCreated, &result, "create tool"); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+func updateTool(ctx context.Context, id string, payload any) (*Tool, error) {
+	var result Tool
+	if err := apiRequest(ctx, http.MethodPatch, "/tool/"+id, payload, http.StatusOK, &result, "update tool"); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// DeleteTool deletes the tool with the given ID
+func DeleteTool(ctx context.Context, id string) error {
+	return apiRequest(ctx, http.MethodDelete, "/tool/"+id, nil, http.StatusOK, nil, "delete tool")
+}
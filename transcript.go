@@ -0,0 +1,179 @@
+package vapi
+
+import (
+	"math"
+	"strings"
+	"time"
+)
+
+// Roles of the messages in Call.Messages and Artifact.Messages
+const (
+	RoleSystem         = "system"
+	RoleBot            = "bot"
+	RoleAssistant      = "assistant"
+	RoleUser           = "user"
+	RoleToolCalls      = "tool_calls"
+	RoleToolCallResult = "tool_call_result"
+)
+
+// Kinds of TranscriptEntry
+const (
+	EntrySpeech     = "speech"
+	EntrySystem     = "system"
+	EntryToolCall   = "tool-call"
+	EntryToolResult = "tool-result"
+)
+
+// Default speaker names, as in Vapi's flat transcript
+const (
+	DefaultAssistantName = "AI"
+	DefaultUserName      = "User"
+)
+
+// TranscriptEntry is one turn of a conversation, or a tool call, tool result
+// or system message between turns. Start and End are offsets from the start
+// of the call.
+type TranscriptEntry struct {
+	Kind string
+	// Role is RoleAssistant or RoleUser for speech, and the message role for
+	// the other kinds
+	Role    string
+	Speaker string
+	Text    string
+	Start   time.Duration
+	End     time.Duration
+
+	// Set on EntryToolCall
+	ToolCalls []ToolCall
+
+	// Set on EntryToolResult
+	ToolCallID string
+	Name       string
+	Result     string
+}
+
+// Transcript is the conversation of a call, with consecutive fragments of
+// speech from the same speaker merged into one turn.
+type Transcript struct {
+	Entries []TranscriptEntry
+}
+
+// NewTranscript builds a transcript from call messages. Speakers are named
+// from plan when it sets names, and DefaultAssistantName and
+// DefaultUserName otherwise.
+func NewTranscript(messages []Message, plan *TranscriptPlan) *Transcript {
+	assistantName, userName := DefaultAssistantName, DefaultUserName
+	if plan != nil {
+		if plan.AssistantName != "" {
+			assistantName = plan.AssistantName
+		}
+		if plan.UserName != "" {
+			userName = plan.UserName
+		}
+	}
+
+	t := &Transcript{}
+	for _, m := range messages {
+		start := seconds(m.SecondsFromStart)
+		end := start
+		switch {
+		case m.Duration > 0:
+			end = start + milliseconds(m.Duration)
+		case m.EndTime > m.Time:
+			end = start + milliseconds(m.EndTime-m.Time)
+		}
+
+		entry := TranscriptEntry{Role: m.Role, Text: strings.TrimSpace(m.Message), Start: start, End: end}
+		switch m.Role {
+		case RoleBot, RoleAssistant:
+			entry.Kind, entry.Role, entry.Speaker = EntrySpeech, RoleAssistant, assistantName
+		case RoleUser:
+			entry.Kind, entry.Speaker = EntrySpeech, userName
+		case RoleToolCalls:
+			entry.Kind, entry.ToolCalls = EntryToolCall, m.ToolCalls
+		case RoleToolCallResult:
+			entry.Kind, entry.ToolCallID, entry.Name, entry.Result = EntryToolResult, m.ToolCallID, m.Name, m.Result
+		default:
+			entry.Kind = EntrySystem
+		}
+
+		if entry.Kind == EntrySpeech && entry.Text == "" {
+			continue
+		}
+		if n := len(t.Entries); n > 0 && entry.Kind == EntrySpeech {
+			last := &t.Entries[n-1]
+			if last.Kind == EntrySpeech && last.Role == entry.Role {
+				last.Text += " " + entry.Text
+				if entry.End > last.End {
+					last.End = entry.End
+				}
+				continue
+			}
+		}
+		t.Entries = append(t.Entries, entry)
+	}
+	return t
+}
+
+// TranscriptFromCall builds the transcript of a call, from its messages or
+// its artifact's messages.
+func TranscriptFromCall(c *Call) *Transcript {
+	messages := c.Messages
+	if len(messages) == 0 && c.Artifact != nil {
+		messages = c.Artifact.Messages
+	}
+	var plan *TranscriptPlan
+	if c.ArtifactPlan != nil {
+		plan = &c.ArtifactPlan.TranscriptPlan
+	}
+	return NewTranscript(messages, plan)
+}
+
+// TranscriptFromReport builds the transcript of the call in an end-of-call
+// report.
+func TranscriptFromReport(r *EndOfCallReport) *Transcript {
+	messages := r.Messages
+	if r.Artifact != nil && len(r.Artifact.Messages) > 0 {
+		messages = r.Artifact.Messages
+	}
+	var plan *TranscriptPlan
+	if r.Call != nil && r.Call.ArtifactPlan != nil {
+		plan = &r.Call.ArtifactPlan.TranscriptPlan
+	}
+	if len(messages) == 0 && r.Call != nil {
+		return TranscriptFromCall(r.Call)
+	}
+	return NewTranscript(messages, plan)
+}
+
+// Turns returns the speech entries only
+func (t *Transcript) Turns() []TranscriptEntry {
+	var turns []TranscriptEntry
+	for _, e := range t.Entries {
+		if e.Kind == EntrySpeech {
+			turns = append(turns, e)
+		}
+	}
+	return turns
+}
+
+// String returns the turns as "Speaker: text" lines, like
+// Artifact.Transcript.
+func (t *Transcript) String() string {
+	var b strings.Builder
+	for _, e := range t.Turns() {
+		b.WriteString(e.Speaker)
+		b.WriteString(": ")
+		b.WriteString(e.Text)
+		b.WriteString("\n")
+	}
+	return b.String()
+}
+
+func seconds(s float64) time.Duration {
+	return time.Duration(math.Round(s * float64(time.Second)))
+}
+
+func milliseconds(ms float64) time.Duration {
+	return time.Duration(math.Round(ms * float64(time.Millisecond)))
+}
@@ -0,0 +1,236 @@
+package vapi
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+)
+
+// defaultCueDuration is how long a caption stays up when its message has no
+// duration or end time
+const defaultCueDuration = 2 * time.Second
+
+// ExportOptions configures the transcript exporters
+type ExportOptions struct {
+	// AssistantLabel and UserLabel replace the speaker names of the
+	// transcript when set
+	AssistantLabel string
+	UserLabel      string
+
+	// KeepOverlaps keeps turns that run into the next turn as they are. By
+	// default a turn is cut off where the next one starts, so captions never
+	// overlap.
+	KeepOverlaps bool
+
+	// IncludeTools adds tool calls and results to JSONL and Markdown exports.
+	// Captions only ever contain speech.
+	IncludeTools bool
+}
+
+func (o ExportOptions) speaker(e TranscriptEntry) string {
+	switch {
+	case e.Role == RoleAssistant && o.AssistantLabel != "":
+		return o.AssistantLabel
+	case e.Role == RoleUser && o.UserLabel != "":
+		return o.UserLabel
+	}
+	return e.Speaker
+}
+
+// cues returns the speech turns ordered by start, with end times filled in
+// and, unless KeepOverlaps is set, clipped to the start of the next turn.
+// Blank lines are removed from the text, since they end a cue in both SRT
+// and WebVTT.
+func (t *Transcript) cues(opts ExportOptions) []TranscriptEntry {
+	turns := t.Turns()
+	sort.SliceStable(turns, func(i, j int) bool { return turns[i].Start < turns[j].Start })
+	for i := range turns {
+		turns[i].Speaker = opts.speaker(turns[i])
+		turns[i].Text = cueText(turns[i].Text)
+		if turns[i].End <= turns[i].Start {
+			turns[i].End = turns[i].Start + defaultCueDuration
+		}
+		if opts.KeepOverlaps || i == len(turns)-1 {
+			continue
+		}
+		if next := turns[i+1].Start; turns[i].End > next && next > turns[i].Start {
+			turns[i].End = next
+		}
+	}
+	return turns
+}
+
+// cueText drops the blank lines from s, keeping its other line breaks
+func cueText(s string) string {
+	var lines []string
+	for _, line := range strings.Split(s, "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			lines = append(lines, line)
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// WriteWebVTT writes the speech turns as WebVTT captions, with the speaker
+// in a voice tag.
+func (t *Transcript) WriteWebVTT(w io.Writer, opts ExportOptions) error {
+	var b strings.Builder
+	b.WriteString("WEBVTT\n")
+	for i, c := range t.cues(opts) {
+		fmt.Fprintf(&b, "\n%d\n%s --> %s\n<v %s>%s\n", i+1,
+			captionTime(c.Start, '.'), captionTime(c.End, '.'),
+			vttEscape(c.Speaker), vttEscape(c.Text))
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// WriteSRT writes the speech turns as SubRip captions, each prefixed with
+// the speaker.
+func (t *Transcript) WriteSRT(w io.Writer, opts ExportOptions) error {
+	var b strings.Builder
+	for i, c := range t.cues(opts) {
+		if i > 0 {
+			b.WriteString("\n")
+		}
+		fmt.Fprintf(&b, "%d\n%s --> %s\n%s: %s\n", i+1,
+			captionTime(c.Start, ','), captionTime(c.End, ','), c.Speaker, c.Text)
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// transcriptRecord is one line of a JSONL export. Times are in seconds from
+// the start of the call.
+type transcriptRecord struct {
+	Kind       string     `json:"kind"`
+	Role       string     `json:"role"`
+	Speaker    string     `json:"speaker,omitempty"`
+	Text       string     `json:"text,omitempty"`
+	Start      float64    `json:"start"`
+	End        float64    `json:"end"`
+	ToolCalls  []ToolCall `json:"toolCalls,omitempty"`
+	ToolCallID string     `json:"toolCallId,omitempty"`
+	Name       string     `json:"name,omitempty"`
+	Result     string     `json:"result,omitempty"`
+}
+
+func newTranscriptRecord(e TranscriptEntry) transcriptRecord {
+	return transcriptRecord{
+		Kind:       e.Kind,
+		Role:       e.Role,
+		Speaker:    e.Speaker,
+		Text:       e.Text,
+		Start:      e.Start.Seconds(),
+		End:        e.End.Seconds(),
+		ToolCalls:  e.ToolCalls,
+		ToolCallID: e.ToolCallID,
+		Name:       e.Name,
+		Result:     e.Result,
+	}
+}
+
+// WriteJSONL writes one JSON object per turn, and per tool call and result
+// when IncludeTools is set.
+func (t *Transcript) WriteJSONL(w io.Writer, opts ExportOptions) error {
+	enc := json.NewEncoder(w)
+	for _, e := range t.exported(opts) {
+		if err := enc.Encode(newTranscriptRecord(e)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// WriteMarkdown writes the transcript for pasting into a ticket: one
+// paragraph per turn with the speaker in bold and the time it started. Tool
+// call arguments go in a fenced code block; all other text is escaped.
+func (t *Transcript) WriteMarkdown(w io.Writer, opts ExportOptions) error {
+	var b strings.Builder
+	for i, e := range t.exported(opts) {
+		if i > 0 {
+			b.WriteString("\n")
+		}
+		stamp := markdownTime(e.Start)
+		switch e.Kind {
+		case EntrySpeech:
+			fmt.Fprintf(&b, "**%s** [%s]: %s\n", markdownEscape(e.Speaker), stamp, markdownEscape(e.Text))
+		case EntryToolCall:
+			for _, call := range e.ToolCalls {
+				fmt.Fprintf(&b, "> [%s] tool call %s\n", stamp, markdownEscape(call.Function.Name))
+				writeMarkdownFence(&b, call.Function.Arguments)
+			}
+		case EntryToolResult:
+			fmt.Fprintf(&b, "> [%s] tool result %s: %s\n", stamp, markdownEscape(e.Name), markdownEscape(e.Result))
+		}
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// exported returns the entries JSONL and Markdown exports contain
+func (t *Transcript) exported(opts ExportOptions) []TranscriptEntry {
+	var entries []TranscriptEntry
+	for _, e := range t.Entries {
+		switch {
+		case e.Kind == EntrySpeech:
+			e.Speaker = opts.speaker(e)
+		case opts.IncludeTools && (e.Kind == EntryToolCall || e.Kind == EntryToolResult):
+		default:
+			continue
+		}
+		entries = append(entries, e)
+	}
+	return entries
+}
+
+// captionTime formats d as hh:mm:ss.mmm, with sep before the milliseconds
+func captionTime(d time.Duration, sep byte) string {
+	ms := d.Milliseconds()
+	return fmt.Sprintf("%02d:%02d:%02d%c%03d", ms/3600000, ms/60000%60, ms/1000%60, sep, ms%1000)
+}
+
+// markdownTime formats d as m:ss
+func markdownTime(d time.Duration) string {
+	s := int(d.Seconds())
+	return fmt.Sprintf("%d:%02d", s/60, s%60)
+}
+
+var vttReplacer = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;", "\n", " ")
+
+func vttEscape(s string) string {
+	return vttReplacer.Replace(s)
+}
+
+var markdownReplacer = strings.NewReplacer(
+	"\\", "\\\\", "*", "\\*", "_", "\\_", "`", "\\`", "~", "\\~", "#", "\\#", "|", "\\|",
+	"[", "\\[", "]", "\\]", "<", "\\<", ">", "\\>", "\n", " ",
+)
+
+func markdownEscape(s string) string {
+	return markdownReplacer.Replace(s)
+}
+
+// writeMarkdownFence writes code as a fenced block inside a block quote. The
+// fence is longer than any run of backticks in code, so code cannot end it.
+func writeMarkdownFence(b *strings.Builder, code string) {
+	fence, run := 3, 0
+	for _, c := range code {
+		if c != '`' {
+			run = 0
+			continue
+		}
+		if run++; run >= fence {
+			fence = run + 1
+		}
+	}
+	ticks := strings.Repeat("`", fence)
+	fmt.Fprintf(b, "> %sjson\n", ticks)
+	for _, line := range strings.Split(code, "\n") {
+		fmt.Fprintf(b, "> %s\n", line)
+	}
+	fmt.Fprintf(b, "> %s\n", ticks)
+}
@@ -0,0 +1,208 @@
+package vapi
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+)
+
+const reportFixture = `{
+	"type": "end-of-call-report",
+	"endedReason": "customer-ended-call",
+	"call": {"id": "call-1", "artifactPlan": {"transcriptPlan": {"enabled": true, "assistantName": "Alex", "userName": "Caller"}}},
+	"artifact": {
+		"messages": [
+			{"role": "system", "message": "You are Alex.", "time": 1000, "secondsFromStart": 0},
+			{"role": "bot", "message": "Hi, this is Alex.", "time": 1500, "endTime": 3000, "secondsFromStart": 0.5, "duration": 1500},
+			{"role": "bot", "message": "How can I help?", "time": 3100, "endTime": 4000, "secondsFromStart": 2.1, "duration": 900},
+			{"role": "user", "message": "Where is my order?", "time": 5000, "endTime": 6200, "secondsFromStart": 4, "duration": 1200},
+			{"role": "tool_calls", "time": 6500, "secondsFromStart": 5.5, "toolCalls": [
+				{"id": "tc-1", "type": "function", "function": {"name": "lookupOrder", "arguments": "{\"id\":42}"}}
+			]},
+			{"role": "tool_call_result", "time": 6900, "secondsFromStart": 5.9, "name": "lookupOrder", "toolCallId": "tc-1", "result": "shipped"},
+			{"role": "bot", "message": "It has shipped.", "time": 7300, "endTime": 8300, "secondsFromStart": 6.3, "duration": 1000},
+			{"role": "user", "message": "Thanks", "time": 8000, "endTime": 8800, "secondsFromStart": 7, "duration": 800},
+			{"role": "user", "message": "bye.", "time": 9000, "endTime": 9400, "secondsFromStart": 8, "duration": 400}
+		]
+	}
+}`
+
+func loadReportFixture(t *testing.T) *EndOfCallReport {
+	t.Helper()
+	var report EndOfCallReport
+	if err := json.Unmarshal([]byte(reportFixture), &report); err != nil {
+		t.Fatal(err)
+	}
+	return &report
+}
+
+func TestTranscriptFromReport(t *testing.T) {
+	tr := TranscriptFromReport(loadReportFixture(t))
+
+	kinds := ""
+	for _, e := range tr.Entries {
+		kinds += e.Kind + " "
+	}
+	if want := "system speech speech tool-call tool-result speech speech "; kinds != want {
+		t.Fatalf("entry kinds = %s, want %s", kinds, want)
+	}
+
+	greeting := tr.Entries[1]
+	if greeting.Speaker != "Alex" || greeting.Text != "Hi, this is Alex. How can I help?" {
+		t.Errorf("merged turn = %+v", greeting)
+	}
+	if greeting.Start != 500*time.Millisecond || greeting.End != 3*time.Second {
+		t.Errorf("merged turn spans %v-%v, want 500ms-3s", greeting.Start, greeting.End)
+	}
+
+	call := tr.Entries[3]
+	if len(call.ToolCalls) != 1 || call.ToolCalls[0].Function.Name != "lookupOrder" || call.ToolCalls[0].Function.Arguments != `{"id":42}` {
+		t.Errorf("tool call entry = %+v", call)
+	}
+	if result := tr.Entries[4]; result.ToolCallID != "tc-1" || result.Result != "shipped" {
+		t.Errorf("tool result entry = %+v", result)
+	}
+
+	want := "Alex: Hi, this is Alex. How can I help?\nCaller: Where is my order?\nAlex: It has shipped.\nCaller: Thanks bye.\n"
+	if got := tr.String(); got != want {
+		t.Errorf("String() =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestToolCallFunction_ObjectArguments(t *testing.T) {
+	var f ToolCallFunction
+	if err := json.Unmarshal([]byte(`{"name": "lookup", "arguments": {"id": 42}}`), &f); err != nil {
+		t.Fatal(err)
+	}
+	if f.Arguments != `{"id": 42}` {
+		t.Errorf("Arguments = %q", f.Arguments)
+	}
+
+	// Object arguments are written back as an object, string ones as a string
+	b, err := json.Marshal(f)
+	if err != nil || string(b) != `{"name":"lookup","arguments":{"id":42}}` {
+		t.Errorf("Marshal() = %s, %v", b, err)
+	}
+	f.Arguments = `{"id":7}`
+	if b, err := json.Marshal(f); err != nil || string(b) != `{"name":"lookup","arguments":"{\"id\":7}"}` {
+		t.Errorf("Marshal() after edit = %s, %v", b, err)
+	}
+}
+
+func TestTranscriptExport(t *testing.T) {
+	tr := TranscriptFromReport(loadReportFixture(t))
+
+	var vtt strings.Builder
+	if err := tr.WriteWebVTT(&vtt, ExportOptions{AssistantLabel: "Agent"}); err != nil {
+		t.Fatal(err)
+	}
+	// "It has shipped." runs until 7.3s but the caller starts at 7s
+	wantVTT := `WEBVTT
+
+1
+00:00:00.500 --> 00:00:03.000
+<v Agent>Hi, this is Alex. How can I help?
+
+2
+00:00:04.000 --> 00:00:05.200
+<v Caller>Where is my order?
+
+3
+00:00:06.300 --> 00:00:07.000
+<v Agent>It has shipped.
+
+4
+00:00:07.000 --> 00:00:08.400
+<v Caller>Thanks bye.
+`
+	if vtt.String() != wantVTT {
+		t.Errorf("WebVTT =\n%s\nwant\n%s", vtt.String(), wantVTT)
+	}
+
+	var srt strings.Builder
+	tr.WriteSRT(&srt, ExportOptions{KeepOverlaps: true})
+	if !strings.Contains(srt.String(), "3\n00:00:06,300 --> 00:00:07,300\nAlex: It has shipped.\n") {
+		t.Errorf("SRT with overlaps =\n%s", srt.String())
+	}
+
+	// Out of order turns are sorted, and blank lines cannot end a cue early
+	unordered := &Transcript{Entries: []TranscriptEntry{
+		{Kind: EntrySpeech, Role: RoleUser, Speaker: "Caller", Text: "Second.\n\n\nStill second.", Start: 3 * time.Second, End: 4 * time.Second},
+		{Kind: EntrySpeech, Role: RoleAssistant, Speaker: "Alex", Text: "First.", Start: time.Second, End: 2 * time.Second},
+	}}
+	srt.Reset()
+	unordered.WriteSRT(&srt, ExportOptions{})
+	wantSRT := "1\n00:00:01,000 --> 00:00:02,000\nAlex: First.\n\n2\n00:00:03,000 --> 00:00:04,000\nCaller: Second.\nStill second.\n"
+	if srt.String() != wantSRT {
+		t.Errorf("SRT of unordered turns =\n%s\nwant\n%s", srt.String(), wantSRT)
+	}
+
+	var jsonl strings.Builder
+	tr.WriteJSONL(&jsonl, ExportOptions{IncludeTools: true})
+	lines := strings.Split(strings.TrimSpace(jsonl.String()), "\n")
+	if len(lines) != 6 {
+		t.Fatalf("JSONL has %d lines, want 6:\n%s", len(lines), jsonl.String())
+	}
+	var record map[string]any
+	if err := json.Unmarshal([]byte(lines[2]), &record); err != nil || record["kind"] != EntryToolCall {
+		t.Errorf("JSONL line 3 = %s", lines[2])
+	}
+
+	var md strings.Builder
+	tr.WriteMarkdown(&md, ExportOptions{IncludeTools: true})
+	for _, want := range []string{"**Alex** [0:00]: Hi, this is Alex.", "> [0:05] tool call lookupOrder\n> ```json\n> {\"id\":42}\n> ```\n", "**Caller** [0:07]: Thanks bye."} {
+		if !strings.Contains(md.String(), want) {
+			t.Errorf("Markdown missing %q:\n%s", want, md.String())
+		}
+	}
+
+	// Names and text are escaped, arguments cannot close their fence
+	tr = &Transcript{Entries: []TranscriptEntry{
+		{Kind: EntrySpeech, Role: "user", Speaker: "**Bob** <img>", Text: "# see [here](x)"},
+		{Kind: EntryToolCall, ToolCalls: []ToolCall{{Function: ToolCallFunction{Name: "look_up", Arguments: "{\"q\":\"```\"}"}}}},
+	}}
+	md.Reset()
+	tr.WriteMarkdown(&md, ExportOptions{IncludeTools: true, UserLabel: "**Bob** <img>"})
+	want := "**\\*\\*Bob\\*\\* \\<img\\>** [0:00]: \\# see \\[here\\](x)\n\n> [0:00] tool call look\\_up\n> ````json\n> {\"q\":\"```\"}\n> ````\n"
+	if md.String() != want {
+		t.Errorf("escaped Markdown =\n%s\nwant\n%s", md.String(), want)
+	}
+}
+
+func TestAnalyzeCall(t *testing.T) {
+	report := loadReportFixture(t)
+	call := report.Call
+	call.Artifact = report.Artifact
+	call.Assistant = &Assistant{Metadata: map[string]any{MetadataPromptName: "intro", MetadataPromptVersion: "1.2.0"}}
+
+	m := AnalyzeCall(call)
+	if m.Responses != 1 || m.ResponseLatencyP50 != 1100*time.Millisecond || m.ResponseLatencyP95 != 1100*time.Millisecond {
+		t.Errorf("latency = %d responses p50 %v p95 %v, want 1 at 1.1s", m.Responses, m.ResponseLatencyP50, m.ResponseLatencyP95)
+	}
+	if m.Interruptions != 1 {
+		t.Errorf("Interruptions = %d, want 1", m.Interruptions)
+	}
+	if m.LongestSilence != 1100*time.Millisecond {
+		t.Errorf("LongestSilence = %v, want 1.1s", m.LongestSilence)
+	}
+	if m.AssistantTalkTime != 3500*time.Millisecond || m.UserTalkTime != 2600*time.Millisecond {
+		t.Errorf("talk time = %v / %v", m.AssistantTalkTime, m.UserTalkTime)
+	}
+	if got := m.TalkRatio; got < 0.573 || got > 0.574 {
+		t.Errorf("TalkRatio = %f, want 3.5/6.1", got)
+	}
+	if m.TimeToFirstAssistantUtterance != 500*time.Millisecond {
+		t.Errorf("TimeToFirstAssistantUtterance = %v", m.TimeToFirstAssistantUtterance)
+	}
+	if m.Duration != 8400*time.Millisecond || m.Turns != 4 || m.TurnsPerMinute < 28.5 || m.TurnsPerMinute > 28.6 {
+		t.Errorf("duration %v, %d turns, %.2f turns/min", m.Duration, m.Turns, m.TurnsPerMinute)
+	}
+	if m.PromptName != "intro" || m.PromptVersion != "1.2.0" {
+		t.Errorf("prompt = %s@%s", m.PromptName, m.PromptVersion)
+	}
+
+	if empty := AnalyzeTranscript(&Transcript{}); empty.TimeToFirstAssistantUtterance != -1 || empty.TurnsPerMinute != 0 {
+		t.Errorf("empty transcript metrics = %+v", empty)
+	}
+}
@@ -0,0 +1,252 @@
+package vapi
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+	"strings"
+)
+
+// e164Pattern matches an E.164 phone number such as +14155550123
+var e164Pattern = regexp.MustCompile(`^\+[1-9]\d{1,14}$`)
+
+// ValidationError describes a single invalid field. Field uses the JSON path
+// of the value, e.g. "assistant.voice.stability".
+type ValidationError struct {
+	Field   string
+	Message string
+}
+
+func (e ValidationError) Error() string {
+	return fmt.Sprintf("%s: %s", e.Field, e.Message)
+}
+
+// ValidationErrors is every violation found while validating a value, so all
+// of them can be fixed at once instead of one Vapi request at a time.
+type ValidationErrors []ValidationError
+
+func (e ValidationErrors) Error() string {
+	msgs := make([]string, len(e))
+	for i, err := range e {
+		msgs[i] = err.Error()
+	}
+	return fmt.Sprintf("%d validation error(s): %s", len(e), strings.Join(msgs, "; "))
+}
+
+// validator accumulates errors. In partial mode required fields are not
+// enforced, which is how assistant overrides are checked.
+type validator struct {
+	errs    ValidationErrors
+	partial bool
+}
+
+func (v *validator) addf(field, format string, args ...any) {
+	v.errs = append(v.errs, ValidationError{Field: field, Message: fmt.Sprintf(format, args...)})
+}
+
+func (v *validator) err() error {
+	if len(v.errs) == 0 {
+		return nil
+	}
+	return v.errs
+}
+
+func (v *validator) required(field, value string) {
+	if !v.partial && value == "" {
+		v.addf(field, "is required")
+	}
+}
+
+func (v *validator) between(field string, value, lo, hi float64) {
+	if value < lo || value > hi {
+		v.addf(field, "must be between %g and %g, got %g", lo, hi, value)
+	}
+}
+
+func (v *validator) exclusive(a, b string, aSet, bSet bool) {
+	if aSet && bSet {
+		v.addf(a, "cannot be set together with %s", b)
+	}
+}
+
+// enums checks every string field of the struct s that carries an enum tag
+func (v *validator) enums(path string, s any) {
+	rv := reflect.ValueOf(s)
+	if rv.Kind() == reflect.Ptr {
+		rv = rv.Elem()
+	}
+	rt := rv.Type()
+	for i := 0; i < rt.NumField(); i++ {
+		tag := rt.Field(i).Tag.Get("enum")
+		if tag == "" {
+			continue
+		}
+
+		fv := rv.Field(i)
+		if fv.Kind() == reflect.Ptr {
+			if fv.IsNil() {
+				continue
+			}
+			fv = fv.Elem()
+		}
+		if fv.Kind() != reflect.String || fv.String() == "" {
+			continue
+		}
+
+		allowed := strings.Split(tag, ",")
+		found := false
+		for _, a := range allowed {
+			if fv.String() == a {
+				found = true
+				break
+			}
+		}
+		if !found {
+			name := strings.Split(rt.Field(i).Tag.Get("json"), ",")[0]
+			v.addf(joinPath(path, name), "must be one of %s, got %q", strings.Join(allowed, ", "), fv.String())
+		}
+	}
+}
+
+// Validate checks the voice configuration
+func (c *ElevenLabsVoiceConfig) Validate() error {
+	var v validator
+	c.validate(&v, "voice")
+	return v.err()
+}
+
+func (c *ElevenLabsVoiceConfig) validate(v *validator, path string) {
+	v.required(joinPath(path, "provider"), c.Provider)
+	v.required(joinPath(path, "voiceId"), c.VoiceID)
+	v.enums(path, c)
+	v.between(joinPath(path, "stability"), c.Stability, 0, 1)
+	v.between(joinPath(path, "similarityBoost"), c.SimilarityBoost, 0, 1)
+	v.between(joinPath(path, "style"), c.Style, 0, 1)
+	v.between(joinPath(path, "optimizeStreamingLatency"), c.OptimizeStreamingLatency, 0, 4)
+}
+
+// Validate checks the model configuration
+func (c *ModelConfig) Validate() error {
+	var v validator
+	c.validate(&v, "model")
+	return v.err()
+}
+
+func (c *ModelConfig) validate(v *validator, path string) {
+	v.required(joinPath(path, "provider"), c.Provider)
+	v.required(joinPath(path, "model"), c.Model)
+	v.enums(path, c)
+	v.between(joinPath(path, "temperature"), c.Temperature, 0, 2)
+	if c.MaxTokens < 0 {
+		v.addf(joinPath(path, "maxTokens"), "must not be negative, got %g", c.MaxTokens)
+	}
+	for i, m := range c.Messages {
+		if m.Role == "" {
+			v.addf(fmt.Sprintf("%s.messages[%d].role", path, i), "is required")
+		}
+	}
+	for i, t := range c.Tools {
+		v.required(fmt.Sprintf("%s.tools[%d].type", path, i), t.Type)
+	}
+}
+
+// Validate checks the success evaluation plan
+func (p *SuccessEvaluationPlan) Validate() error {
+	var v validator
+	p.validate(&v, "successEvaluationPlan")
+	return v.err()
+}
+
+func (p *SuccessEvaluationPlan) validate(v *validator, path string) {
+	v.enums(path, p)
+	for i, m := range p.Messages {
+		if m.Role == "" {
+			v.addf(fmt.Sprintf("%s.messages[%d].role", path, i), "is required")
+		}
+	}
+	if p.TimeoutSeconds != nil {
+		v.between(joinPath(path, "timeoutSeconds"), float64(*p.TimeoutSeconds), 1, 60)
+	}
+}
+
+// Validate checks the customer. Numbers must be E.164 unless the E.164 check
+// has been explicitly disabled.
+func (c *Customer) Validate() error {
+	var v validator
+	c.validate(&v, "customer")
+	return v.err()
+}
+
+func (c *Customer) validate(v *validator, path string) {
+	checkE164 := c.NumberE164CheckEnabled == nil || *c.NumberE164CheckEnabled
+	if c.Number != "" && checkE164 && !e164Pattern.MatchString(c.Number) {
+		v.addf(joinPath(path, "number"), "must be an E.164 number like +14155550123, got %q", c.Number)
+	}
+	if !v.partial && c.Number == "" && c.SipURI == "" {
+		v.addf(joinPath(path, "number"), "is required when sipUri is not set")
+	}
+}
+
+// Validate checks the assistant configuration before it is sent to Vapi
+func (a *Assistant) Validate() error {
+	var v validator
+	a.validate(&v, "")
+	return v.err()
+}
+
+func (a *Assistant) validate(v *validator, path string) {
+	v.enums(path, a)
+	if a.Voice != nil {
+		a.Voice.validate(v, joinPath(path, "voice"))
+	}
+	if a.Model != nil {
+		a.Model.validate(v, joinPath(path, "model"))
+	}
+	if a.Transcriber != nil {
+		v.required(joinPath(path, "transcriber.provider"), a.Transcriber.Provider)
+	}
+	if a.SilenceTimeoutSeconds != nil {
+		v.between(joinPath(path, "silenceTimeoutSeconds"), float64(*a.SilenceTimeoutSeconds), 10, 3600)
+	}
+	if a.MaxDurationSeconds != nil {
+		v.between(joinPath(path, "maxDurationSeconds"), float64(*a.MaxDurationSeconds), 10, 43200)
+	}
+	if a.AnalysisPlan != nil && a.AnalysisPlan.SuccessEvaluationPlan != nil {
+		a.AnalysisPlan.SuccessEvaluationPlan.validate(v, joinPath(path, "analysisPlan.successEvaluationPlan"))
+	}
+}
+
+// Validate checks the call request: mutually exclusive fields, the inline
+// assistant and overrides, and the customer number. CreateCall runs it before
+// sending anything.
+func (c *Call) Validate() error {
+	var v validator
+
+	v.exclusive("assistantId", "assistant", c.AssistantID != nil, c.Assistant != nil)
+	v.exclusive("squadId", "squad", c.SquadID != nil, c.Squad != nil)
+	v.exclusive("phoneNumberId", "phoneNumber", c.PhoneNumberID != nil, c.PhoneNumber != nil)
+	v.exclusive("customerId", "customer", c.CustomerID != nil, c.Customer != nil)
+
+	hasAssistant := c.AssistantID != nil || c.Assistant != nil
+	hasSquad := c.SquadID != nil || c.Squad != nil
+	v.exclusive("assistant", "squad", hasAssistant, hasSquad)
+	// Without an assistant or squad Vapi uses the phone number's own
+	hasPhoneNumber := c.PhoneNumberID != nil || c.PhoneNumber != nil
+	if !hasAssistant && !hasSquad && !hasPhoneNumber {
+		v.addf("assistantId", "one of assistantId, assistant, squadId or squad is required without a phone number")
+	}
+
+	if c.Assistant != nil {
+		c.Assistant.validate(&v, "assistant")
+	}
+	if c.AssistantOverrides != nil {
+		overrides := validator{partial: true}
+		c.AssistantOverrides.validate(&overrides, "assistantOverrides")
+		v.errs = append(v.errs, overrides.errs...)
+	}
+	if c.Customer != nil {
+		c.Customer.validate(&v, "customer")
+	}
+
+	return v.err()
+}
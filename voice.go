@@ -1,5 +1,7 @@
 package vapi
 
+import "encoding/json"
+
 // type VoiceConfig struct {
 // 	Provider              string       `json:"provider"`
 // 	VoiceID               string       `json:"voiceId"`
@@ -35,4 +37,6 @@ type ElevenLabsVoiceConfig struct {
 	FillerInjectionEnabled bool `json:"fillerInjectionEnabled,omitempty"`
 
 	UseSpeakerBoost bool `json:"useSpeakerBoost,omitempty"`
+
+	Extra map[string]json.RawMessage `json:"-"`
 }
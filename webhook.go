@@ -1,6 +1,7 @@
 package vapi
 
 import (
+	"encoding/json"
 	"time"
 )
 
@@ -33,6 +34,8 @@ type AssistantRequest struct {
 	Assistant   *Assistant   `json:"assistant,omitempty"`
 	Customer    *Customer    `json:"customer,omitempty"`
 	Call        *Call        `json:"call,omitempty"`
+
+	Extra map[string]json.RawMessage `json:"-"`
 }
 
 type AssistantRequestEnvelope struct {
@@ -46,6 +49,8 @@ type AssistantRequestResponse struct {
 	AssistantOverrides *Assistant   `json:"assistantOverrides,omitempty"`
 	CustomerID         *string      `json:"customerId,omitempty"`
 	Customer           *Customer    `json:"customer,omitempty"`
+
+	Extra map[string]json.RawMessage `json:"-"`
 }
 
 type EndOfCallReport struct {
@@ -73,6 +78,8 @@ type EndOfCallReport struct {
 	Customer           *Customer    `json:"customer,omitempty"`
 
 	Assistant *Assistant `json:"assistant,omitempty"`
+
+	Extra map[string]json.RawMessage `json:"-"`
 }
 
 // EndOfCallReportEnvelope represents the report generated at the end of a call
@@ -92,4 +99,6 @@ type ConversationUpdate struct {
 	CustomerID     *string         `json:"customerId,omitempty"`
 	Customer       *Customer       `json:"customer,omitempty"`
 	Call           *Call           `json:"call,omitempty"`
+
+	Extra map[string]json.RawMessage `json:"-"`
 }